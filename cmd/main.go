@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"dailyDataPanel/internal/conf"
+	"dailyDataPanel/internal/metrics"
+	"dailyDataPanel/internal/server"
 	"dailyDataPanel/internal/services"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 )
 
 var appVersion string = "v1.0.0"
@@ -17,9 +25,17 @@ func main() {
 		configPath = flag.String("config", "config/config.yaml", "配置文件路径")
 		help       = flag.Bool("help", false, "显示帮助信息")
 		version    = flag.Bool("version", false, "显示版本信息")
+		force      = flag.Bool("force", false, "忽略本时间窗口已完成的步骤，强制重新上传、评论与通知")
+		tmplFile   = flag.String("template-file", "", "render命令：预览指定的模板文件")
+		channel    = flag.String("channel", "", "render命令：预览指定通知渠道（NOTIFIERS中的NAME）的模板，默认预览GitLab评论模板")
 	)
 
-	flag.Parse()
+	// 第一个非选项参数为子命令：run（默认，单次执行）、serve/daemon（守护进程）
+	command, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	flag.CommandLine.Parse(args)
 
 	// 显示版本信息
 	if *version {
@@ -30,13 +46,21 @@ func main() {
 	// 显示帮助信息
 	if *help {
 		fmt.Println("用法:")
-		fmt.Println("  dataPanelExport [选项]")
+		fmt.Println("  dataPanelExport [命令] [选项]")
+		fmt.Println("")
+		fmt.Println("命令:")
+		fmt.Println("  run            单次执行导出（默认）")
+		fmt.Println("  serve, daemon  守护进程模式，按REPORTS中的cron表达式调度")
+		fmt.Println("  render         使用示例数据渲染消息模板并输出，用于预览")
 		fmt.Println("")
 		fmt.Println("选项:")
 		flag.PrintDefaults()
 		fmt.Println("")
 		fmt.Println("示例:")
 		fmt.Println("  dataPanelExport --config /path/to/config.yaml")
+		fmt.Println("  dataPanelExport --config /path/to/config.yaml --force")
+		fmt.Println("  dataPanelExport serve --config /path/to/config.yaml")
+		fmt.Println("  dataPanelExport render --config /path/to/config.yaml --channel weixin")
 		os.Exit(0)
 	}
 
@@ -49,5 +73,83 @@ func main() {
 
 	fileCloseFn := conf.InitLogger()
 	defer fileCloseFn()
-	services.Run()
+	defer conf.CloseLogger()
+	logger := conf.GetLogger()
+	logger.Info("初始化完成")
+
+	// SIGTERM/SIGINT 取消ctx，进行中的HTTP请求随之取消
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	var err error
+	switch command {
+	case "run":
+		opts := services.DefaultRunOptions()
+		opts.Force = *force
+		_, err = services.Run(ctx, opts)
+		writeMetricsTextfile()
+	case "serve", "daemon":
+		err = serve(ctx)
+	case "render":
+		var out string
+		if out, err = services.PreviewTemplate(*tmplFile, *channel); err == nil {
+			fmt.Print(out)
+		}
+	default:
+		err = fmt.Errorf("未知命令: %s", command)
+	}
+	if err != nil {
+		logger.Error("导出任务失败: " + err.Error())
+		conf.CloseLogger()
+		fileCloseFn()
+		log.Fatalf("导出任务失败: %v", err)
+	}
+}
+
+// writeMetricsTextfile 单次执行结束后写入textfile collector文件（未配置时跳过）
+func writeMetricsTextfile() {
+	path := conf.GetAppConfig().Metrics.TextfilePath
+	if path == "" {
+		return
+	}
+	if err := metrics.Default.WriteTextfile(path); err != nil {
+		conf.GetLogger().Warn("写入指标文件失败: " + err.Error())
+	}
+}
+
+// serve 守护进程模式：按调度执行并提供控制API，收到退出信号后等待执行中的任务结束
+func serve(ctx context.Context) error {
+	logger := conf.GetLogger()
+	appConf := conf.GetAppConfig()
+	if len(appConf.Reports) == 0 && appConf.Daemon.ListenAddr == "" {
+		return errors.New("守护进程模式需要配置REPORTS或DAEMON.LISTEN_ADDR")
+	}
+
+	// 控制API启动失败时一并停止调度
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	runner := services.NewRunner()
+	var wg sync.WaitGroup
+	if len(appConf.Reports) > 0 {
+		scheduler, err := services.NewScheduler(runner)
+		if err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scheduler.Start(ctx)
+		}()
+	}
+
+	logger.Info("守护进程已启动")
+	var serveErr error
+	if appConf.Daemon.ListenAddr != "" {
+		serveErr = server.New(ctx, runner).ListenAndServe(ctx, appConf.Daemon.ListenAddr)
+		cancel()
+	}
+	wg.Wait()
+	logger.Info("守护进程已退出")
+	return serveErr
 }
@@ -1,111 +1,439 @@
 package api
 
 import (
+	"cmp"
+	"context"
 	"dailyDataPanel/internal/conf"
+	"errors"
 	"fmt"
-	"log"
-	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
 	rds20140815 "github.com/alibabacloud-go/rds-20140815/v16/client"
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/dara"
 	"github.com/alibabacloud-go/tea/tea"
 	credential "github.com/aliyun/credentials-go/credentials"
+	"github.com/aliyun/credentials-go/credentials/providers"
 )
 
-type AliSlowLogResp struct {
-	TotalRecordCount int
-	PageSize         int
-	Records          []*rds20140815.DescribeSlowLogRecordsResponseBodyItemsSQLSlowRecord
+// AliSlowLogRecord 阿里云慢日志明细
+type AliSlowLogRecord = rds20140815.DescribeSlowLogRecordsResponseBodyItemsSQLSlowRecord
+
+// AliSlowLogStat 阿里云按SQL聚合的慢日志统计
+type AliSlowLogStat = rds20140815.DescribeSlowLogsResponseBodyItemsSQLSlowLog
+
+// AliPage 阿里云分页接口的单页结果
+type AliPage[T any] struct {
+	TotalRecordCount int // 查询条件下的总条数
+	PageRecordCount  int // 本页条数
+	Records          []T
 }
 
-func CreateClient() (_result *rds20140815.Client, _err error) {
-	// 工程代码建议使用更安全的无AK方式，凭据配置方式请参见：https://help.aliyun.com/document_detail/378661.html。
-	appConf := conf.GetAppConfig()
-	credential, _err := credential.NewCredential(nil)
-	if _err != nil {
-		return _result, _err
+// AliSlowLogResp 慢日志明细的单页结果
+type AliSlowLogResp = AliPage[*AliSlowLogRecord]
+
+// aliSlowLogPageSize 慢日志明细与统计每页条数（接口上限）
+const aliSlowLogPageSize = 100
+
+// NewAliCredential 按配置的凭据类型创建阿里云凭据，RDS与OSS共用
+func NewAliCredential(cfg conf.AliConfig) (credential.Credential, error) {
+	typ := cfg.CredentialType
+	if typ == "" && cfg.AccessKey != "" {
+		typ = "access_key"
+	}
+	needAK := func() error {
+		if cfg.AccessKey == "" || cfg.AccessSecret == "" {
+			return fmt.Errorf("阿里云凭据类型 %s 需要配置ACCESS_KEY与ACCESS_SECRET", typ)
+		}
+		return nil
 	}
 
-	config := &openapi.Config{
-		Credential:      credential,
-		AccessKeyId:     &appConf.Ali.AccessKey,
-		AccessKeySecret: &appConf.Ali.AccessSecret,
+	switch typ {
+	case "":
+		// 默认凭据链：环境变量、OIDC、凭据文件、ECS实例角色等
+		return credential.NewCredential(nil)
+	case "access_key":
+		if err := needAK(); err != nil {
+			return nil, err
+		}
+		return credential.NewCredential(&credential.Config{
+			Type:            tea.String("access_key"),
+			AccessKeyId:     tea.String(cfg.AccessKey),
+			AccessKeySecret: tea.String(cfg.AccessSecret),
+		})
+	case "sts":
+		if err := needAK(); err != nil {
+			return nil, err
+		}
+		if cfg.SecurityToken == "" {
+			return nil, errors.New("阿里云凭据类型 sts 需要配置SECURITY_TOKEN")
+		}
+		return credential.NewCredential(&credential.Config{
+			Type:            tea.String("sts"),
+			AccessKeyId:     tea.String(cfg.AccessKey),
+			AccessKeySecret: tea.String(cfg.AccessSecret),
+			SecurityToken:   tea.String(cfg.SecurityToken),
+		})
+	case "ram_role_arn":
+		if err := needAK(); err != nil {
+			return nil, err
+		}
+		if cfg.RoleArn == "" {
+			return nil, errors.New("阿里云凭据类型 ram_role_arn 需要配置ROLE_ARN")
+		}
+		config := &credential.Config{
+			Type:            tea.String("ram_role_arn"),
+			AccessKeyId:     tea.String(cfg.AccessKey),
+			AccessKeySecret: tea.String(cfg.AccessSecret),
+			RoleArn:         tea.String(cfg.RoleArn),
+			RoleSessionName: tea.String(cmp.Or(cfg.RoleSessionName, "dataPanelExport")),
+		}
+		if cfg.ExternalID != "" {
+			config.ExternalId = tea.String(cfg.ExternalID)
+		}
+		return credential.NewCredential(config)
+	case "ecs_ram_role":
+		config := &credential.Config{Type: tea.String("ecs_ram_role")}
+		if cfg.RoleName != "" {
+			config.RoleName = tea.String(cfg.RoleName)
+		}
+		return credential.NewCredential(config)
+	case "profile":
+		builder := providers.NewCLIProfileCredentialsProviderBuilder()
+		if cfg.Profile != "" {
+			builder = builder.WithProfileName(cfg.Profile)
+		}
+		if cfg.CredentialsFile != "" {
+			builder = builder.WithProfileFile(cfg.CredentialsFile)
+		}
+		provider, err := builder.Build()
+		if err != nil {
+			return nil, fmt.Errorf("读取阿里云CLI配置失败: %w", err)
+		}
+		return credential.FromCredentialsProvider("cli_profile", provider), nil
+	default:
+		return nil, fmt.Errorf("未知的阿里云凭据类型: %s，可选: access_key, sts, ram_role_arn, ecs_ram_role, profile", typ)
 	}
-	// Endpoint 请参考 https://api.aliyun.com/product/Rds
-	config.Endpoint = tea.String(appConf.Ali.Endpoion)
-	fmt.Println(conf.GetAppConfig().Ali)
-	_result = &rds20140815.Client{}
-	_result, _err = rds20140815.NewClient(config)
-	return _result, _err
 }
 
-func describeSlowLogRecordsAPI(istID string, pageSize, PageNumber int32) (*AliSlowLogResp, error) {
-	appConf := conf.GetAppConfig()
-	// 获取前一天完整的起始和结束时间戳
-	loc, _ := time.LoadLocation("Asia/Shanghai")
-	now := time.Now().In(loc)
-	d := 0 - appConf.Query.LookBackDays
-	if d >= -1 {
-		d = -1
+// newAliOpenAPIConfig 阿里云OpenAPI客户端配置：未配置ENDPOINT时由SDK按REGION解析访问域名
+func newAliOpenAPIConfig(cfg conf.AliConfig) (*openapi.Config, error) {
+	if cfg.Region == "" && cfg.Endpoint == "" {
+		return nil, errors.New("阿里云未配置REGION（或ENDPOINT）")
+	}
+	cred, err := NewAliCredential(cfg)
+	if err != nil {
+		return nil, err
+	}
+	config := &openapi.Config{Credential: cred}
+	if cfg.Region != "" {
+		config.RegionId = tea.String(cfg.Region)
 	}
-	startDay := now.AddDate(0, 0, d)
-	endDay := now.AddDate(0, 0, -1)
-	startTime := time.Date(startDay.Year(), startDay.Month(), startDay.Day(), 0, 0, 0, 0, loc).Format("2006-01-02T15:04Z")
-	endTime := time.Date(endDay.Year(), endDay.Month(), endDay.Day(), 23, 59, 59, 0, loc).Format("2006-01-02T15:04Z")
+	if cfg.Endpoint != "" {
+		config.Endpoint = tea.String(cfg.Endpoint)
+	}
+	return config, nil
+}
+
+// AliRDSClient 阿里云RDS客户端，创建一次后复用；每次调用按超时设置RuntimeOptions，限流错误按退避重试
+type AliRDSClient struct {
+	client      *rds20140815.Client
+	timeout     time.Duration
+	retry       RetryPolicy
+	concurrency int // 同时获取的分页数
+}
+
+// NewAliRDSClient 按阿里云配置创建RDS客户端
+func NewAliRDSClient(cfg conf.AliConfig) (*AliRDSClient, error) {
+	config, err := newAliOpenAPIConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := rds20140815.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("创建阿里云RDS客户端失败: %w", err)
+	}
+	retry := DefaultRetryPolicy()
+	retry.MaxAttempts = cmp.Or(cfg.MaxAttempts, 5)
+	retry.Retryable = IsAliThrottlingError
+	return &AliRDSClient{
+		client:      client,
+		timeout:     time.Duration(cmp.Or(cfg.TimeoutSeconds, 30)) * time.Second,
+		retry:       retry,
+		concurrency: cmp.Or(cfg.PageConcurrency, 4),
+	}, nil
+}
 
-	// 阿里云Client请求API
-	client, _err := CreateClient()
-	if _err != nil {
-		return nil, _err
+// aliErrorCode 阿里云SDK错误的错误码
+func aliErrorCode(err error) string {
+	var daraErr *dara.SDKError
+	if errors.As(err, &daraErr) {
+		return tea.StringValue(daraErr.Code)
 	}
+	var teaErr *tea.SDKError
+	if errors.As(err, &teaErr) {
+		return tea.StringValue(teaErr.Code)
+	}
+	return ""
+}
+
+// IsAliThrottlingError 是否为阿里云限流错误（如Throttling.User），此类错误可退避后重试
+func IsAliThrottlingError(err error) bool {
+	code := aliErrorCode(err)
+	return code == "Throttling" || strings.HasPrefix(code, "Throttling.")
+}
+
+// runtime 单次调用的运行时参数：读写超时取客户端设置，关闭SDK自带重试（由call统一重试）
+func (c *AliRDSClient) runtime() *dara.RuntimeOptions {
+	timeoutMS := int(c.timeout.Milliseconds())
+	return &dara.RuntimeOptions{
+		Autoretry:      tea.Bool(false),
+		ReadTimeout:    tea.Int(timeoutMS),
+		ConnectTimeout: tea.Int(min(timeoutMS, 10000)),
+	}
+}
+
+// call 执行一次API调用，限流错误按重试策略退避重试，ctx结束时停止
+func (c *AliRDSClient) call(ctx context.Context, action string, fn func(runtime *dara.RuntimeOptions) error) error {
+	err := Retry(ctx, c.retry, func(attempt int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(c.runtime())
+	})
+	if err != nil {
+		return fmt.Errorf("阿里云%s调用失败: %w", action, err)
+	}
+	return nil
+}
 
-	describeSlowLogRecordsRequest := &rds20140815.DescribeSlowLogRecordsRequest{
+const (
+	// aliTimeFormat 阿里云接口的时间格式（UTC，精确到分钟）
+	aliTimeFormat = "2006-01-02T15:04Z"
+	// aliDateFormat 按天统计接口的日期格式（UTC）
+	aliDateFormat = "2006-01-02Z"
+)
+
+func (c *AliRDSClient) describeSlowLogRecordsPage(ctx context.Context, istID string, window TimeWindow, pageNumber int) (*AliSlowLogResp, error) {
+	request := &rds20140815.DescribeSlowLogRecordsRequest{
 		DBInstanceId: tea.String(istID),
-		StartTime:    tea.String(startTime),
-		EndTime:      tea.String(endTime),
-		PageSize:     tea.Int32(pageSize),
-		PageNumber:   tea.Int32(PageNumber),
-	}
-	fmt.Println("describeSlowLogRecordsRequest", describeSlowLogRecordsRequest)
-	runtime := &util.RuntimeOptions{}
-	resp, err := client.DescribeSlowLogRecordsWithOptions(describeSlowLogRecordsRequest, runtime)
+		StartTime:    tea.String(window.Start.UTC().Format(aliTimeFormat)),
+		EndTime:      tea.String(window.End.UTC().Format(aliTimeFormat)),
+		PageSize:     tea.Int32(aliSlowLogPageSize),
+		PageNumber:   tea.Int32(int32(pageNumber)),
+	}
+	var resp *rds20140815.DescribeSlowLogRecordsResponse
+	err := c.call(ctx, "DescribeSlowLogRecords", func(runtime *dara.RuntimeOptions) (err error) {
+		resp, err = c.client.DescribeSlowLogRecordsWithContext(ctx, request, runtime)
+		return err
+	})
 	if err != nil {
-		var error = &tea.SDKError{}
-		if _t, ok := err.(*tea.SDKError); ok {
-			error = _t
-		} else {
-			error.Message = tea.String(err.Error())
-		}
-		// logger := conf.GetLogger()
-		// logger.Error(tea.StringValue(error.Message))
-		log.Println(tea.StringValue(error.Message))
 		return nil, err
 	}
-	res := resp.Body.Items.SQLSlowRecord
-	return &AliSlowLogResp{
-		TotalRecordCount: int(*resp.Body.TotalRecordCount),
-		PageSize:         int(*resp.Body.PageRecordCount),
-		Records:          res,
-	}, nil
+	if resp.Body == nil {
+		return &AliSlowLogResp{}, nil
+	}
+	out := &AliSlowLogResp{
+		TotalRecordCount: int(tea.Int32Value(resp.Body.TotalRecordCount)),
+		PageRecordCount:  int(tea.Int32Value(resp.Body.PageRecordCount)),
+	}
+	if resp.Body.Items != nil {
+		out.Records = resp.Body.Items.SQLSlowRecord
+	}
+	return out, nil
+}
+
+// DescribeSlowLogRecords 获取实例在时间窗口内的全部慢日志明细。
+// 时间窗口按天拆分以避免接口的时间范围与分页上限截断数据，每天的分页并发获取，
+// 并按TotalRecordCount校验条数；相邻两天在边界分钟内重复返回的记录会去重
+func (c *AliRDSClient) DescribeSlowLogRecords(ctx context.Context, istID string, window TimeWindow) ([]*AliSlowLogRecord, error) {
+	var (
+		all      []*AliSlowLogRecord
+		boundary map[string]int
+	)
+	for _, day := range dailyWindows(window) {
+		fetch := func(ctx context.Context, page int) (*AliSlowLogResp, error) {
+			return c.describeSlowLogRecordsPage(ctx, istID, day, page)
+		}
+		records, err := fetchAllPages(ctx, fetch, c.concurrency)
+		if err != nil {
+			return nil, fmt.Errorf("获取 %s 的慢日志失败: %w", day.StartDate(), err)
+		}
+		all = append(all, dropBoundaryDuplicates(records, boundary, day.Start)...)
+		boundary = boundaryRecords(records, day.End)
+	}
+	return all, nil
+}
+
+// inMinute 记录的执行时间是否落在t所在的分钟内（接口时间为UTC）
+func inMinute(r *AliSlowLogRecord, t time.Time) bool {
+	return strings.HasPrefix(tea.StringValue(r.ExecutionStartTime), strings.TrimSuffix(t.UTC().Format(aliTimeFormat), "Z"))
+}
+
+// boundaryRecords 统计执行时间落在边界分钟内的记录（去重键 -> 条数）
+func boundaryRecords(records []*AliSlowLogRecord, boundary time.Time) map[string]int {
+	counts := make(map[string]int)
+	for _, r := range records {
+		if inMinute(r, boundary) {
+			counts[slowLogRecordKey(r)]++
+		}
+	}
+	return counts
+}
+
+// dropBoundaryDuplicates 去掉上一子窗口已返回的边界分钟内的记录。
+// 只比较边界分钟，其余时间内内容相同的记录是真实的多次执行，不去重
+func dropBoundaryDuplicates(records []*AliSlowLogRecord, previous map[string]int, start time.Time) []*AliSlowLogRecord {
+	if len(previous) == 0 {
+		return records
+	}
+	kept := make([]*AliSlowLogRecord, 0, len(records))
+	for _, r := range records {
+		if inMinute(r, start) {
+			if key := slowLogRecordKey(r); previous[key] > 0 {
+				previous[key]--
+				continue
+			}
+		}
+		kept = append(kept, r)
+	}
+	return kept
 }
 
-func DescribeSlowLogRecords(istID string) ([]*rds20140815.DescribeSlowLogRecordsResponseBodyItemsSQLSlowRecord, error) {
-	// 核心：计算总页数，然后进入循环，最后组合切片数据结果。
-	pageSize := 100
-	aliResp, err := describeSlowLogRecordsAPI(istID, int32(pageSize), 1)
+// dailyWindows 将时间窗口按自然日拆分，相邻子窗口首尾相接（接口按分钟取整，边界记录可能重复）
+func dailyWindows(window TimeWindow) []TimeWindow {
+	end := window.End.Add(time.Second).Truncate(time.Minute)
+	var windows []TimeWindow
+	for start := window.Start; start.Before(end); {
+		next := time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, start.Location())
+		if next.After(end) {
+			next = end
+		}
+		windows = append(windows, TimeWindow{Start: start, End: next})
+		start = next
+	}
+	return windows
+}
+
+// fetchAllPages 先获取第一页得到总条数，再以concurrency为上限并发获取其余分页（页码从1开始），
+// 按页码顺序合并，合并后的条数须与TotalRecordCount一致
+func fetchAllPages[T any](ctx context.Context, fetch func(ctx context.Context, page int) (*AliPage[T], error), concurrency int) ([]T, error) {
+	first, err := fetch(ctx, 1)
 	if err != nil {
 		return nil, err
 	}
-	allRes := slices.Clone(aliResp.Records)
-	totalPages := (aliResp.TotalRecordCount / pageSize) + 1
-	for i := 2; i <= totalPages; i++ {
-		aliResp, err := describeSlowLogRecordsAPI(istID, int32(pageSize), int32(i))
+	total := first.TotalRecordCount
+	pages := max((total+aliSlowLogPageSize-1)/aliSlowLogPageSize, 1)
+	results := make([]*AliPage[T], pages)
+	results[0] = first
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		sem      = make(chan struct{}, max(concurrency, 1))
+	)
+	for page := 2; page <= pages; page++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			resp, err := fetch(ctx, page)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = fmt.Errorf("第%d页: %w", page, err)
+					cancel()
+				})
+				return
+			}
+			results[page-1] = resp
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var records []T
+	fetched := 0
+	for page, resp := range results {
+		if resp.PageRecordCount != len(resp.Records) {
+			return nil, fmt.Errorf("第%d页PageRecordCount为%d，实际返回%d条", page+1, resp.PageRecordCount, len(resp.Records))
+		}
+		fetched += resp.PageRecordCount
+		records = append(records, resp.Records...)
+	}
+	if fetched != total {
+		return nil, fmt.Errorf("获取到%d条，与TotalRecordCount %d 不一致", fetched, total)
+	}
+	return records, nil
+}
+
+// DescribeSlowLogs 获取实例在时间窗口内按SQL聚合的慢日志统计（每条SQL每天一条记录）
+func (c *AliRDSClient) DescribeSlowLogs(ctx context.Context, istID string, window TimeWindow) ([]*AliSlowLogStat, error) {
+	start, end := statsDateRange(window)
+	fetch := func(ctx context.Context, page int) (*AliPage[*AliSlowLogStat], error) {
+		request := &rds20140815.DescribeSlowLogsRequest{
+			DBInstanceId: tea.String(istID),
+			StartTime:    tea.String(start),
+			EndTime:      tea.String(end),
+			PageSize:     tea.Int32(aliSlowLogPageSize),
+			PageNumber:   tea.Int32(int32(page)),
+		}
+		var resp *rds20140815.DescribeSlowLogsResponse
+		err := c.call(ctx, "DescribeSlowLogs", func(runtime *dara.RuntimeOptions) (err error) {
+			resp, err = c.client.DescribeSlowLogsWithContext(ctx, request, runtime)
+			return err
+		})
 		if err != nil {
 			return nil, err
 		}
-		allRes = append(allRes, aliResp.Records...)
+		out := &AliPage[*AliSlowLogStat]{}
+		if resp.Body == nil {
+			return out, nil
+		}
+		out.TotalRecordCount = int(tea.Int32Value(resp.Body.TotalRecordCount))
+		out.PageRecordCount = int(tea.Int32Value(resp.Body.PageRecordCount))
+		if resp.Body.Items != nil {
+			out.Records = resp.Body.Items.SQLSlowLog
+		}
+		return out, nil
 	}
-	return allRes, nil
+	return fetchAllPages(ctx, fetch, c.concurrency)
+}
+
+// statsDateRange 慢日志统计的起止日期：统计按UTC自然日聚合，取时间窗口起止时刻所在的UTC日期，
+// 与明细查询一样按UTC换算，避免本地日期被当作UTC日期导致时间范围偏移
+func statsDateRange(window TimeWindow) (string, string) {
+	return window.Start.UTC().Format(aliDateFormat), window.End.UTC().Format(aliDateFormat)
+}
+
+// slowLogRecordKey 慢日志明细的去重键
+func slowLogRecordKey(r *AliSlowLogRecord) string {
+	return strings.Join([]string{
+		tea.StringValue(r.ExecutionStartTime),
+		tea.StringValue(r.HostAddress),
+		tea.StringValue(r.DBName),
+		tea.StringValue(r.UserName),
+		strconv.FormatInt(tea.Int64Value(r.QueryTimeMS), 10),
+		strconv.FormatInt(tea.Int64Value(r.LockTimes), 10),
+		strconv.FormatInt(tea.Int64Value(r.ParseRowCounts), 10),
+		strconv.FormatInt(tea.Int64Value(r.ReturnRowCounts), 10),
+		tea.StringValue(r.SQLText),
+	}, "\x00")
 }
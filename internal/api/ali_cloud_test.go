@@ -1,17 +1,199 @@
 package api
 
 import (
+	"context"
 	"dailyDataPanel/internal/conf"
-	"fmt"
-	"log"
+	"os"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/alibabacloud-go/tea/dara"
+	"github.com/alibabacloud-go/tea/tea"
 )
 
-func Test_DescribeSlowLogRecords(T *testing.T) {
-	conf.InitConfigWithPath("/opt/sekorm/dailyDataPanel/config/config.yaml")
-	res, err := DescribeSlowLogRecords("rm-xxxx")
+// 需要真实的阿里云配置，配置文件不存在时跳过
+func Test_DescribeSlowLogRecords(t *testing.T) {
+	const configPath = "/opt/sekorm/dailyDataPanel/config/config.yaml"
+	if _, err := os.Stat(configPath); err != nil {
+		t.Skipf("未找到配置文件 %s", configPath)
+	}
+	conf.InitConfigWithPath(configPath)
+	client, err := NewAliRDSClient(conf.GetAppConfig().Ali)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.DescribeSlowLogRecords(context.Background(), "rm-xxxx", DefaultWindow(conf.GetAppConfig().Query.LookBackDays))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) > 0 {
+		t.Log(">>", res[:1])
+	}
+}
+
+func Test_NewAliCredential(t *testing.T) {
+	cred, err := NewAliCredential(conf.AliConfig{AccessKey: "ak", AccessSecret: "sk"})
+	if err != nil || *cred.GetType() != "access_key" {
+		t.Fatalf("配置ACCESS_KEY时应默认使用access_key: %v", err)
+	}
+
+	cred, err = NewAliCredential(conf.AliConfig{CredentialType: "sts", AccessKey: "ak", AccessSecret: "sk", SecurityToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := cred.GetCredential()
+	if err != nil || *model.SecurityToken != "token" {
+		t.Fatalf("sts凭据应携带令牌: %v", err)
+	}
+
+	for _, cfg := range []conf.AliConfig{
+		{CredentialType: "sts", AccessKey: "ak", AccessSecret: "sk"},
+		{CredentialType: "ram_role_arn", AccessKey: "ak", AccessSecret: "sk"},
+		{CredentialType: "access_key"},
+		{CredentialType: "unknown"},
+	} {
+		if _, err := NewAliCredential(cfg); err == nil {
+			t.Fatalf("凭据配置不完整时应返回错误: %+v", cfg)
+		}
+	}
+}
+
+func Test_AliRDSClientRetryThrottling(t *testing.T) {
+	c := &AliRDSClient{
+		timeout: time.Second,
+		retry:   RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Retryable: IsAliThrottlingError},
+	}
+	throttled := dara.NewSDKError(map[string]any{"code": "Throttling.User", "message": "Request was denied due to user flow control."})
+
+	calls := 0
+	err := c.call(context.Background(), "DescribeSlowLogRecords", func(runtime *dara.RuntimeOptions) error {
+		calls++
+		if tea.IntValue(runtime.ReadTimeout) != 1000 || tea.BoolValue(runtime.Autoretry) {
+			t.Errorf("运行时参数不符合预期: %+v", runtime)
+		}
+		if calls < 3 {
+			return throttled
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("限流错误应重试至成功: calls=%d err=%v", calls, err)
+	}
+
+	// 非限流错误不重试
+	calls = 0
+	err = c.call(context.Background(), "DescribeSlowLogRecords", func(runtime *dara.RuntimeOptions) error {
+		calls++
+		return dara.NewSDKError(map[string]any{"code": "InvalidDBInstanceId.NotFound", "message": "not found"})
+	})
+	if err == nil || calls != 1 {
+		t.Fatalf("非限流错误不应重试: calls=%d err=%v", calls, err)
+	}
+
+	// ctx已取消时不发起调用
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	if err := c.call(ctx, "DescribeSlowLogRecords", func(*dara.RuntimeOptions) error { calls++; return nil }); err == nil || calls != 0 {
+		t.Fatalf("ctx取消后应直接返回: calls=%d err=%v", calls, err)
+	}
+}
+
+func Test_DailyWindows(t *testing.T) {
+	window, err := NewTimeWindow("2026-10-12", "2026-10-14")
 	if err != nil {
-		log.Fatalln(err)
+		t.Fatal(err)
+	}
+	days := dailyWindows(window)
+	if len(days) != 3 {
+		t.Fatalf("应拆分为3天: %v", days)
+	}
+	if !days[0].Start.Equal(window.Start) || !days[0].End.Equal(days[1].Start) || days[2].End.Format(time.DateTime) != "2026-10-15 00:00:00" {
+		t.Fatalf("子窗口边界不符合预期: %v", days)
+	}
+}
+
+// 只去掉相邻两天在边界分钟内重复返回的记录，其余内容相同的记录保留
+func Test_DropBoundaryDuplicates(t *testing.T) {
+	boundary := time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)
+	record := func(at string) *AliSlowLogRecord {
+		return &AliSlowLogRecord{ExecutionStartTime: tea.String(at), SQLText: tea.String("select 1")}
+	}
+	previousDay := []*AliSlowLogRecord{
+		record("2026-10-12T08:00:00Z"),
+		record("2026-10-12T08:00:00Z"),
+		record("2026-10-13T00:00:30Z"),
+	}
+	currentDay := []*AliSlowLogRecord{
+		record("2026-10-13T00:00:30Z"),
+		record("2026-10-13T00:00:30Z"),
+		record("2026-10-13T08:00:00Z"),
+		record("2026-10-13T08:00:00Z"),
+	}
+	if got := dropBoundaryDuplicates(previousDay, nil, boundary.AddDate(0, 0, -1)); len(got) != 3 {
+		t.Fatalf("第一天不应去重: %d", len(got))
+	}
+	got := dropBoundaryDuplicates(currentDay, boundaryRecords(previousDay, boundary), boundary)
+	if len(got) != 3 {
+		t.Fatalf("只应去掉1条边界重复记录，实际保留 %d 条", len(got))
+	}
+}
+
+// 统计的起止日期按UTC换算（UTC+8的时间窗口从前一天16:00Z开始）
+func Test_StatsDateRange(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	window := TimeWindow{
+		Start: time.Date(2026, 10, 12, 0, 0, 0, 0, loc),
+		End:   time.Date(2026, 10, 18, 23, 59, 59, 0, loc),
+	}
+	if start, end := statsDateRange(window); start != "2026-10-11Z" || end != "2026-10-18Z" {
+		t.Fatalf("统计日期不符合预期: %s - %s", start, end)
+	}
+}
+
+func Test_FetchAllPages(t *testing.T) {
+	const total = 250
+	records := make([]*AliSlowLogRecord, total)
+	for i := range records {
+		records[i] = &AliSlowLogRecord{SQLText: tea.String(strconv.Itoa(i))}
+	}
+	var (
+		mu    sync.Mutex
+		pages []int
+	)
+	fetch := func(ctx context.Context, page int) (*AliSlowLogResp, error) {
+		mu.Lock()
+		pages = append(pages, page)
+		mu.Unlock()
+		start := (page - 1) * aliSlowLogPageSize
+		end := min(start+aliSlowLogPageSize, total)
+		return &AliSlowLogResp{TotalRecordCount: total, PageRecordCount: end - start, Records: records[start:end]}, nil
+	}
+	got, err := fetchAllPages(context.Background(), fetch, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != total || len(pages) != 3 || tea.StringValue(got[total-1].SQLText) != "249" {
+		t.Fatalf("分页结果不符合预期: %d条，请求页码 %v", len(got), pages)
+	}
+
+	// 恰为整页时不应多请求一页
+	pages = nil
+	exact := func(ctx context.Context, page int) (*AliSlowLogResp, error) {
+		pages = append(pages, page)
+		return &AliSlowLogResp{TotalRecordCount: aliSlowLogPageSize, PageRecordCount: aliSlowLogPageSize, Records: records[:aliSlowLogPageSize]}, nil
+	}
+	if _, err := fetchAllPages(context.Background(), exact, 2); err != nil || len(pages) != 1 {
+		t.Fatalf("整页时应只请求1页: pages=%v err=%v", pages, err)
+	}
+
+	// 条数与TotalRecordCount不一致时返回错误
+	short := func(ctx context.Context, page int) (*AliSlowLogResp, error) {
+		return &AliSlowLogResp{TotalRecordCount: 150, PageRecordCount: 50, Records: records[:50]}, nil
+	}
+	if _, err := fetchAllPages(context.Background(), short, 2); err == nil {
+		t.Fatal("条数不一致时应返回错误")
 	}
-	fmt.Println(">>", res[:1])
 }
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DingTalkRobotAPI 封装钉钉自定义机器人操作
+type DingTalkRobotAPI struct {
+	client   *HTTPClient
+	url      string
+	secret   string   // 加签密钥
+	keywords []string // 自定义关键词
+	now      func() time.Time
+}
+
+// DingTalkRobotError 钉钉接口返回的业务错误（HTTP状态码为200）
+type DingTalkRobotError struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func (e *DingTalkRobotError) Error() string {
+	return fmt.Sprintf("钉钉接口错误: errcode=%d, errmsg=%s", e.ErrCode, e.ErrMsg)
+}
+
+// DingTalkAt 消息中需要@的成员
+type DingTalkAt struct {
+	AtMobiles []string `json:"atMobiles,omitempty"`
+	AtUserIDs []string `json:"atUserIds,omitempty"`
+	IsAtAll   bool     `json:"isAtAll,omitempty"`
+}
+
+// DingTalkButton actionCard按钮
+type DingTalkButton struct {
+	Title     string `json:"title"`
+	ActionURL string `json:"actionURL"`
+}
+
+// DingTalkActionCard actionCard消息，仅一个按钮时使用整体跳转
+type DingTalkActionCard struct {
+	Title   string
+	Text    string
+	Buttons []DingTalkButton
+}
+
+// NewDingTalkRobotAPI 创建钉钉机器人客户端，secret为空时不签名
+func NewDingTalkRobotAPI(webhookURL, secret string, keywords []string) *DingTalkRobotAPI {
+	return &DingTalkRobotAPI{
+		client:   NewDefaultHTTPClient(),
+		url:      webhookURL,
+		secret:   secret,
+		keywords: keywords,
+		now:      time.Now,
+	}
+}
+
+// DingTalkSign 计算加签参数：base64(HmacSHA256(secret, timestamp+"\n"+secret))
+func DingTalkSign(timestamp int64, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signedURL 追加timestamp与sign参数
+func (d *DingTalkRobotAPI) signedURL() (string, error) {
+	if d.secret == "" {
+		return d.url, nil
+	}
+	u, err := url.Parse(d.url)
+	if err != nil {
+		return "", fmt.Errorf("解析Webhook地址失败: %w", err)
+	}
+	timestamp := d.now().UnixMilli()
+	q := u.Query()
+	q.Set("timestamp", strconv.FormatInt(timestamp, 10))
+	q.Set("sign", DingTalkSign(timestamp, d.secret))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// withKeyword 消息不含任一自定义关键词时，在末尾补充第一个关键词
+func (d *DingTalkRobotAPI) withKeyword(text string) string {
+	if len(d.keywords) == 0 {
+		return text
+	}
+	for _, kw := range d.keywords {
+		if strings.Contains(text, kw) {
+			return text
+		}
+	}
+	return strings.TrimRight(text, "\n") + "\n\n" + d.keywords[0]
+}
+
+// send 发送消息并解析errcode
+func (d *DingTalkRobotAPI) send(ctx context.Context, payload any) error {
+	webhookURL, err := d.signedURL()
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.PostJSON(ctx, webhookURL, payload, nil)
+	if err != nil {
+		return err
+	}
+	var result DingTalkRobotError
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		return fmt.Errorf("解析钉钉响应失败: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return &result
+	}
+	return nil
+}
+
+// SendMarkdown 发送Markdown消息，被@的手机号与userid会追加到正文以便高亮
+func (d *DingTalkRobotAPI) SendMarkdown(ctx context.Context, title, text string, at DingTalkAt) error {
+	var mentions []string
+	for _, mobile := range at.AtMobiles {
+		mentions = append(mentions, "@"+mobile)
+	}
+	for _, id := range at.AtUserIDs {
+		mentions = append(mentions, "@"+id)
+	}
+	if len(mentions) > 0 {
+		text = strings.TrimRight(text, "\n") + "\n\n" + strings.Join(mentions, " ")
+	}
+
+	payload := map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": title,
+			"text":  d.withKeyword(text),
+		},
+		"at": at,
+	}
+	if err := d.send(ctx, payload); err != nil {
+		return fmt.Errorf("发送钉钉机器人消息失败: %w", err)
+	}
+	return nil
+}
+
+// SendActionCard 发送actionCard消息（不支持@成员）
+func (d *DingTalkRobotAPI) SendActionCard(ctx context.Context, card DingTalkActionCard) error {
+	if len(card.Buttons) == 0 {
+		return fmt.Errorf("actionCard消息至少需要一个按钮")
+	}
+	actionCard := map[string]any{
+		"title": card.Title,
+		"text":  d.withKeyword(card.Text),
+	}
+	if len(card.Buttons) == 1 {
+		actionCard["singleTitle"] = card.Buttons[0].Title
+		actionCard["singleURL"] = card.Buttons[0].ActionURL
+	} else {
+		actionCard["btns"] = card.Buttons
+		actionCard["btnOrientation"] = "0"
+	}
+	payload := map[string]any{
+		"msgtype":    "actionCard",
+		"actionCard": actionCard,
+	}
+	if err := d.send(ctx, payload); err != nil {
+		return fmt.Errorf("发送钉钉机器人actionCard消息失败: %w", err)
+	}
+	return nil
+}
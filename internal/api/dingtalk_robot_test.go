@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_DingTalkRobotSendMarkdown(t *testing.T) {
+	var payload struct {
+		MsgType  string `json:"msgtype"`
+		Markdown struct {
+			Title string `json:"title"`
+			Text  string `json:"text"`
+		} `json:"markdown"`
+		At DingTalkAt `json:"at"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("access_token") != "tk" || q.Get("timestamp") != "1760832000000" || q.Get("sign") != DingTalkSign(1760832000000, "SECabc") {
+			writeTestJSON(w, map[string]any{"errcode": 310000, "errmsg": "sign not match"})
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		writeTestJSON(w, map[string]any{"errcode": 0, "errmsg": "ok"})
+	}))
+	defer srv.Close()
+
+	d := NewDingTalkRobotAPI(srv.URL+"?access_token=tk", "SECabc", []string{"慢查询"})
+	d.now = func() time.Time { return time.UnixMilli(1760832000000) }
+	err := d.SendMarkdown(context.Background(), "报表", "数据已更新", DingTalkAt{AtMobiles: []string{"13800000000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.MsgType != "markdown" || !strings.HasSuffix(payload.Markdown.Text, "慢查询") ||
+		!strings.Contains(payload.Markdown.Text, "@13800000000") || payload.At.AtMobiles[0] != "13800000000" {
+		t.Fatalf("消息内容不符合预期: %+v", payload)
+	}
+
+	// 签名错误等业务错误返回DingTalkRobotError
+	d.secret = "SECwrong"
+	var dtErr *DingTalkRobotError
+	if err := d.SendMarkdown(context.Background(), "报表", "慢查询", DingTalkAt{}); !errors.As(err, &dtErr) || dtErr.ErrCode != 310000 {
+		t.Fatalf("期望返回钉钉错误，实际: %v", err)
+	}
+}
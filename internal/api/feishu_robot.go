@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// FeishuRobotAPI 封装飞书/Lark自定义机器人操作
+type FeishuRobotAPI struct {
+	client *HTTPClient
+	url    string
+	secret string // 签名校验密钥
+	now    func() time.Time
+}
+
+// FeishuRobotError 飞书接口返回的业务错误
+type FeishuRobotError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+func (e *FeishuRobotError) Error() string {
+	return fmt.Sprintf("飞书接口错误: code=%d, msg=%s", e.Code, e.Msg)
+}
+
+// FeishuCard 消息卡片
+type FeishuCard struct {
+	Config   map[string]any `json:"config,omitempty"`
+	Header   *FeishuHeader  `json:"header,omitempty"`
+	Elements []any          `json:"elements"`
+}
+
+// FeishuHeader 卡片标题
+type FeishuHeader struct {
+	Title    FeishuText `json:"title"`
+	Template string     `json:"template,omitempty"` // 标题颜色，如 blue、red、orange
+}
+
+// FeishuText 文本元素
+type FeishuText struct {
+	Tag     string `json:"tag"` // plain_text 或 lark_md
+	Content string `json:"content"`
+}
+
+// FeishuField 并排展示的字段
+type FeishuField struct {
+	IsShort bool       `json:"is_short"`
+	Text    FeishuText `json:"text"`
+}
+
+// FeishuButton 跳转按钮
+type FeishuButton struct {
+	Title string
+	URL   string
+	Type  string // default、primary、danger
+}
+
+// NewFeishuRobotAPI 创建飞书机器人客户端，secret为空时不签名
+func NewFeishuRobotAPI(webhookURL, secret string) *FeishuRobotAPI {
+	return &FeishuRobotAPI{
+		client: NewDefaultHTTPClient(),
+		url:    webhookURL,
+		secret: secret,
+		now:    time.Now,
+	}
+}
+
+// FeishuSign 计算签名：以timestamp+"\n"+secret为密钥对空串做HmacSHA256后base64
+func FeishuSign(timestamp int64, secret string) string {
+	mac := hmac.New(sha256.New, []byte(strconv.FormatInt(timestamp, 10)+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewFeishuCard 创建带标题的卡片
+func NewFeishuCard(title, template string) *FeishuCard {
+	return &FeishuCard{
+		Config: map[string]any{"wide_screen_mode": true},
+		Header: &FeishuHeader{
+			Title:    FeishuText{Tag: "plain_text", Content: title},
+			Template: template,
+		},
+	}
+}
+
+// AddMarkdown 添加Markdown文本
+func (c *FeishuCard) AddMarkdown(content string) *FeishuCard {
+	c.Elements = append(c.Elements, map[string]any{"tag": "markdown", "content": content})
+	return c
+}
+
+// AddFields 添加并排字段
+func (c *FeishuCard) AddFields(fields []FeishuField) *FeishuCard {
+	c.Elements = append(c.Elements, map[string]any{"tag": "div", "fields": fields})
+	return c
+}
+
+// AddTable 添加表格（以分栏模拟），weights为各列宽度权重
+func (c *FeishuCard) AddTable(headers []string, weights []int, rows [][]string) *FeishuCard {
+	row := func(cells []string, bold bool) map[string]any {
+		columns := make([]map[string]any, 0, len(cells))
+		for i, cell := range cells {
+			if bold {
+				cell = "**" + cell + "**"
+			}
+			columns = append(columns, map[string]any{
+				"tag":            "column",
+				"width":          "weighted",
+				"weight":         weights[i],
+				"vertical_align": "top",
+				"elements":       []any{map[string]any{"tag": "markdown", "content": cell}},
+			})
+		}
+		return map[string]any{"tag": "column_set", "flex_mode": "none", "columns": columns}
+	}
+	c.Elements = append(c.Elements, row(headers, true))
+	for _, r := range rows {
+		c.Elements = append(c.Elements, row(r, false))
+	}
+	return c
+}
+
+// AddButtons 添加跳转按钮
+func (c *FeishuCard) AddButtons(buttons []FeishuButton) *FeishuCard {
+	actions := make([]map[string]any, 0, len(buttons))
+	for _, b := range buttons {
+		typ := b.Type
+		if typ == "" {
+			typ = "default"
+		}
+		actions = append(actions, map[string]any{
+			"tag":  "button",
+			"text": FeishuText{Tag: "plain_text", Content: b.Title},
+			"url":  b.URL,
+			"type": typ,
+		})
+	}
+	c.Elements = append(c.Elements, map[string]any{"tag": "action", "actions": actions})
+	return c
+}
+
+// send 发送消息并解析code
+func (f *FeishuRobotAPI) send(ctx context.Context, payload map[string]any) error {
+	if f.secret != "" {
+		timestamp := f.now().Unix()
+		payload["timestamp"] = strconv.FormatInt(timestamp, 10)
+		payload["sign"] = FeishuSign(timestamp, f.secret)
+	}
+	resp, err := f.client.PostJSON(ctx, f.url, payload, nil)
+	if err != nil {
+		return err
+	}
+	var result FeishuRobotError
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		return fmt.Errorf("解析飞书响应失败: %w", err)
+	}
+	if result.Code != 0 {
+		return &result
+	}
+	return nil
+}
+
+// SendText 发送文本消息
+func (f *FeishuRobotAPI) SendText(ctx context.Context, text string) error {
+	payload := map[string]any{
+		"msg_type": "text",
+		"content":  map[string]string{"text": text},
+	}
+	if err := f.send(ctx, payload); err != nil {
+		return fmt.Errorf("发送飞书机器人消息失败: %w", err)
+	}
+	return nil
+}
+
+// SendCard 发送消息卡片
+func (f *FeishuRobotAPI) SendCard(ctx context.Context, card *FeishuCard) error {
+	payload := map[string]any{
+		"msg_type": "interactive",
+		"card":     card,
+	}
+	if err := f.send(ctx, payload); err != nil {
+		return fmt.Errorf("发送飞书机器人卡片消息失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_FeishuRobotSendCard(t *testing.T) {
+	var payload map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload["timestamp"] != "1760832000" || payload["sign"] != FeishuSign(1760832000, "secret") {
+			writeTestJSON(w, map[string]any{"code": 19021, "msg": "sign match fail or timestamp is not within one hour from current time"})
+			return
+		}
+		writeTestJSON(w, map[string]any{"code": 0, "msg": "success", "data": map[string]any{}})
+	}))
+	defer srv.Close()
+
+	f := NewFeishuRobotAPI(srv.URL, "secret")
+	f.now = func() time.Time { return time.Unix(1760832000, 0) }
+	card := NewFeishuCard("报表", "blue").
+		AddFields([]FeishuField{{IsShort: true, Text: FeishuText{Tag: "lark_md", Content: "**RDS**\n10条"}}}).
+		AddTable([]string{"#", "SQL"}, []int{1, 5}, [][]string{{"1", "select ?"}}).
+		AddButtons([]FeishuButton{{Title: "GitLab Issue", URL: "https://gitlab.example.com/-/issues/9"}})
+	if err := f.SendCard(context.Background(), card); err != nil {
+		t.Fatal(err)
+	}
+	if payload["msg_type"] != "interactive" {
+		t.Fatalf("消息类型不符合预期: %v", payload["msg_type"])
+	}
+	elements := payload["card"].(map[string]any)["elements"].([]any)
+	if len(elements) != 4 {
+		t.Fatalf("卡片元素数量不符合预期: %d", len(elements))
+	}
+
+	f.secret = "wrong"
+	var fsErr *FeishuRobotError
+	if err := f.SendText(context.Background(), "hello"); !errors.As(err, &fsErr) || fsErr.Code != 19021 {
+		t.Fatalf("期望返回飞书错误，实际: %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"strings"
 )
 
 // GitLabAPI 封装GitLab API操作
@@ -15,6 +16,7 @@ type GitLabAPI struct {
 	accessToken string
 	projectID   uint
 	issueIID    uint
+	issueURL    string // 已解析的Issue地址，评论时不再重复请求
 }
 
 func NewGitLabAPI() *GitLabAPI {
@@ -28,24 +30,240 @@ func NewGitLabAPI() *GitLabAPI {
 	}
 }
 
-// CommentCreate 创建评论
-func (g *GitLabAPI) CommentCreate(ctx context.Context, msg string) error {
+// GitLabProject 项目信息
+type GitLabProject struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+	DefaultBranch     string `json:"default_branch"` // 空仓库时为空
+}
+
+// GitLabIssue Issue信息
+type GitLabIssue struct {
+	ID     int    `json:"id"`
+	IID    int    `json:"iid"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	WebURL string `json:"web_url"`
+}
+
+// GitLabNote 评论信息
+type GitLabNote struct {
+	ID   int    `json:"id"`
+	Body string `json:"body"`
+	URL  string `json:"url"` // 评论永久链接（Issue地址#note_ID），由客户端补全
+}
+
+// GitLabIssueOptions 新建Issue的参数
+type GitLabIssueOptions struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Labels      string `json:"labels,omitempty"` // 逗号分隔
+	AssigneeIDs []int  `json:"assignee_ids,omitempty"`
+	MilestoneID int    `json:"milestone_id,omitempty"`
+	DueDate     string `json:"due_date,omitempty"` // yyyy-mm-dd
+}
+
+// WithIssue 返回指向另一个Issue的客户端副本（评论、Issue地址等均基于该Issue）
+func (g *GitLabAPI) WithIssue(issueIID uint) *GitLabAPI {
+	cp := *g
+	cp.issueIID = issueIID
+	cp.issueURL = ""
+	return &cp
+}
+
+// apiURL 拼接API地址
+func (g *GitLabAPI) apiURL(format string, args ...any) string {
+	return strings.TrimSuffix(g.url, "/") + "/api/v4" + fmt.Sprintf(format, args...)
+}
+
+// headers 认证请求头
+func (g *GitLabAPI) headers() map[string]string {
+	return map[string]string{
+		"PRIVATE-TOKEN": g.accessToken,
+	}
+}
+
+// getJSON 发送GET请求并解析JSON响应
+func (g *GitLabAPI) getJSON(ctx context.Context, url string, v any) error {
+	resp, err := g.client.Get(ctx, url, &RequestOptions{Headers: g.headers()})
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(resp.Body, v); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}
+
+// putJSON 发送JSON格式的PUT请求
+func (g *GitLabAPI) putJSON(ctx context.Context, url string, payload any) (*Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	headers := g.headers()
+	headers["Content-Type"] = "application/json"
+	return g.client.Put(ctx, url, &RequestOptions{Headers: headers, Body: body})
+}
+
+// Project 获取项目信息（含web_url）
+func (g *GitLabAPI) Project(ctx context.Context) (*GitLabProject, error) {
+	var project GitLabProject
+	if err := g.getJSON(ctx, g.apiURL("/projects/%d", g.projectID), &project); err != nil {
+		return nil, fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	return &project, nil
+}
+
+// Issue 获取Issue信息（含web_url）
+func (g *GitLabAPI) Issue(ctx context.Context, issueIID uint) (*GitLabIssue, error) {
+	var issue GitLabIssue
+	if err := g.getJSON(ctx, g.apiURL("/projects/%d/issues/%d", g.projectID, issueIID), &issue); err != nil {
+		return nil, fmt.Errorf("获取Issue信息失败: %w", err)
+	}
+	return &issue, nil
+}
+
+// CreateIssue 新建Issue
+func (g *GitLabAPI) CreateIssue(ctx context.Context, opts GitLabIssueOptions) (*GitLabIssue, error) {
+	resp, err := g.client.PostJSON(ctx, g.apiURL("/projects/%d/issues", g.projectID), opts, g.headers())
+	if err != nil {
+		return nil, fmt.Errorf("创建Issue失败: %w", err)
+	}
+	var issue GitLabIssue
+	if err := json.Unmarshal(resp.Body, &issue); err != nil {
+		return nil, fmt.Errorf("解析Issue响应失败: %w", err)
+	}
+	return &issue, nil
+}
+
+// CloseIssue 关闭Issue
+func (g *GitLabAPI) CloseIssue(ctx context.Context, issueIID uint) error {
+	payload := map[string]string{"state_event": "close"}
+	if _, err := g.putJSON(ctx, g.apiURL("/projects/%d/issues/%d", g.projectID, issueIID), payload); err != nil {
+		return fmt.Errorf("关闭Issue失败: %w", err)
+	}
+	return nil
+}
+
+// LinkIssue 将当前Issue与同项目的另一个Issue关联（relates_to）
+func (g *GitLabAPI) LinkIssue(ctx context.Context, targetIID uint) error {
+	payload := map[string]any{
+		"target_project_id": g.projectID,
+		"target_issue_iid":  targetIID,
+	}
+	_, err := g.client.PostJSON(ctx, g.apiURL("/projects/%d/issues/%d/links", g.projectID, g.issueIID), payload, g.headers())
+	if err != nil {
+		return fmt.Errorf("关联Issue失败: %w", err)
+	}
+	return nil
+}
+
+// IssueURL 配置中Issue的web地址，解析成功后缓存在客户端上
+func (g *GitLabAPI) IssueURL(ctx context.Context) (string, error) {
+	if g.issueURL != "" {
+		return g.issueURL, nil
+	}
+	issue, err := g.Issue(ctx, g.issueIID)
+	if err != nil {
+		return "", err
+	}
+	g.issueURL = issue.WebURL
+	return issue.WebURL, nil
+}
+
+// AbsoluteURL 将上传接口返回的相对地址（/uploads/...）补全为项目下的绝对地址
+func (p *GitLabProject) AbsoluteURL(relative string) string {
+	if relative == "" || strings.HasPrefix(relative, "http://") || strings.HasPrefix(relative, "https://") {
+		return relative
+	}
+	return strings.TrimSuffix(p.WebURL, "/") + relative
+}
+
+// CommentCreate 创建评论，返回评论ID与永久链接（评论已创建但无法获取Issue地址时URL为空）
+func (g *GitLabAPI) CommentCreate(ctx context.Context, msg string) (*GitLabNote, error) {
 	payload := map[string]string{
 		"body": msg,
 	}
 
-	headers := map[string]string{
-		"PRIVATE-TOKEN": g.accessToken,
+	url := g.apiURL("/projects/%d/issues/%d/notes", g.projectID, g.issueIID)
+
+	resp, err := g.client.PostJSON(ctx, url, payload, g.headers())
+	if err != nil {
+		return nil, fmt.Errorf("创建评论失败: %w", err)
 	}
 
-	url := g.url + fmt.Sprintf("/api/v4/projects/%d/issues/%d/notes", g.projectID, g.issueIID)
+	var note GitLabNote
+	if err := json.Unmarshal(resp.Body, &note); err != nil {
+		return nil, fmt.Errorf("解析评论响应失败: %w", err)
+	}
 
-	_, err := g.client.PostJSON(ctx, url, payload, headers)
+	if issueURL, err := g.IssueURL(ctx); err == nil {
+		note.URL = NotePermalink(issueURL, note.ID)
+	}
+	return &note, nil
+}
+
+// FindNote 按创建时间倒序分页查找正文包含marker的评论，找到后不再请求后续分页，不存在时返回nil
+func (g *GitLabAPI) FindNote(ctx context.Context, marker string) (*GitLabNote, error) {
+	page := "1"
+	for page != "" {
+		url := g.apiURL("/projects/%d/issues/%d/notes?sort=desc&order_by=created_at&per_page=100&page=%s", g.projectID, g.issueIID, page)
+		resp, err := g.client.Get(ctx, url, &RequestOptions{Headers: g.headers()})
+		if err != nil {
+			return nil, fmt.Errorf("获取评论列表失败: %w", err)
+		}
+		var batch []GitLabNote
+		if err := json.Unmarshal(resp.Body, &batch); err != nil {
+			return nil, fmt.Errorf("解析评论列表失败: %w", err)
+		}
+		for i := range batch {
+			if strings.Contains(batch[i].Body, marker) {
+				return &batch[i], nil
+			}
+		}
+		page = resp.Headers.Get("X-Next-Page")
+		if len(batch) == 0 {
+			break
+		}
+	}
+	return nil, nil
+}
+
+// UpdateNote 更新评论内容
+func (g *GitLabAPI) UpdateNote(ctx context.Context, noteID int, msg string) (*GitLabNote, error) {
+	payload := map[string]string{"body": msg}
+	resp, err := g.putJSON(ctx, g.apiURL("/projects/%d/issues/%d/notes/%d", g.projectID, g.issueIID, noteID), payload)
 	if err != nil {
-		return fmt.Errorf("创建评论失败: %w", err)
+		return nil, fmt.Errorf("更新评论失败: %w", err)
 	}
+	var note GitLabNote
+	if err := json.Unmarshal(resp.Body, &note); err != nil {
+		return nil, fmt.Errorf("解析评论响应失败: %w", err)
+	}
+	if issueURL, err := g.IssueURL(ctx); err == nil {
+		note.URL = NotePermalink(issueURL, note.ID)
+	}
+	return &note, nil
+}
 
-	return nil
+// CommentMarker 评论中的隐藏标记（HTML注释，渲染后不可见），用于定位同一时间窗口的评论
+func CommentMarker(key string) string {
+	return "<!-- dataPanelExport:" + key + " -->"
+}
+
+// CommentUpsert 若已存在带有marker的评论则原地更新，否则新建评论；marker会追加在正文末尾
+func (g *GitLabAPI) CommentUpsert(ctx context.Context, marker, msg string) (*GitLabNote, error) {
+	body := strings.TrimRight(msg, "\n") + "\n\n" + marker
+	existing, err := g.FindNote(ctx, marker)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return g.UpdateNote(ctx, existing.ID, body)
+	}
+	return g.CommentCreate(ctx, body)
 }
 
 // UploadFile 上传文件并返回markdown字符串引用文本
@@ -59,13 +277,9 @@ func (g *GitLabAPI) UploadFile(ctx context.Context, filePath string) (string, er
 		},
 	}
 
-	headers := map[string]string{
-		"PRIVATE-TOKEN": g.accessToken,
-	}
-
-	url := g.url + fmt.Sprintf("/api/v4/projects/%d/uploads", g.projectID)
+	url := g.apiURL("/projects/%d/uploads", g.projectID)
 
-	resp, err := g.client.PostMultipart(ctx, url, nil, files, headers)
+	resp, err := g.client.PostMultipart(ctx, url, nil, files, g.headers())
 	if err != nil {
 		return "", fmt.Errorf("上传文件失败: %w", err)
 	}
@@ -83,3 +297,8 @@ func (g *GitLabAPI) UploadFile(ctx context.Context, filePath string) (string, er
 
 	return uploadResponse.Markdown, nil
 }
+
+// NotePermalink 评论永久链接
+func NotePermalink(issueURL string, noteID int) string {
+	return fmt.Sprintf("%s#note_%d", issueURL, noteID)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// GitLabPackage 软件包（通用软件包仓库中的一个版本）
+type GitLabPackage struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GenericPackageURL 通用软件包文件的下载地址（私有项目需携带令牌访问）
+func (g *GitLabAPI) GenericPackageURL(name, version, fileName string) string {
+	return g.apiURL("/projects/%d/packages/generic/%s/%s/%s", g.projectID,
+		url.PathEscape(name), url.PathEscape(version), url.PathEscape(fileName))
+}
+
+// PackageURL 软件包在项目软件包仓库中的页面地址（登录后可下载，适合作为消息中的链接）
+func (p *GitLabProject) PackageURL(packageID int) string {
+	return fmt.Sprintf("%s/-/packages/%d", strings.TrimSuffix(p.WebURL, "/"), packageID)
+}
+
+// UploadGenericPackage 以fileName上传本地文件到通用软件包仓库，上传后下载校验SHA-256，返回下载地址
+func (g *GitLabAPI) UploadGenericPackage(ctx context.Context, name, version, fileName, filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("读取文件失败: %w", err)
+	}
+	fileURL := g.GenericPackageURL(name, version, fileName)
+	headers := g.headers()
+	headers["Content-Type"] = "application/octet-stream"
+	if _, err := g.client.Put(ctx, fileURL, &RequestOptions{Headers: headers, Body: content}); err != nil {
+		return "", fmt.Errorf("上传软件包文件失败: %w", err)
+	}
+
+	resp, err := g.client.Get(ctx, fileURL, &RequestOptions{Headers: g.headers()})
+	if err != nil {
+		return "", fmt.Errorf("下载软件包文件校验失败: %w", err)
+	}
+	want, got := sha256.Sum256(content), sha256.Sum256(resp.Body)
+	if !bytes.Equal(want[:], got[:]) {
+		return "", fmt.Errorf("软件包文件 %s 校验失败: 本地SHA-256 %s，仓库SHA-256 %s",
+			fileName, hex.EncodeToString(want[:]), hex.EncodeToString(got[:]))
+	}
+	return fileURL, nil
+}
+
+// ListGenericPackages 分页列出指定名称的通用软件包的全部版本
+func (g *GitLabAPI) ListGenericPackages(ctx context.Context, name string) ([]GitLabPackage, error) {
+	var packages []GitLabPackage
+	page := "1"
+	for page != "" {
+		u := g.apiURL("/projects/%d/packages?package_type=generic&package_name=%s&per_page=100&page=%s",
+			g.projectID, url.QueryEscape(name), page)
+		resp, err := g.client.Get(ctx, u, &RequestOptions{Headers: g.headers()})
+		if err != nil {
+			return nil, fmt.Errorf("获取软件包列表失败: %w", err)
+		}
+		var batch []GitLabPackage
+		if err := json.Unmarshal(resp.Body, &batch); err != nil {
+			return nil, fmt.Errorf("解析软件包列表失败: %w", err)
+		}
+		for _, pkg := range batch {
+			// package_name为模糊匹配，需精确过滤
+			if pkg.Name == name {
+				packages = append(packages, pkg)
+			}
+		}
+		page = resp.Headers.Get("X-Next-Page")
+		if len(batch) == 0 {
+			break
+		}
+	}
+	return packages, nil
+}
+
+// DeletePackage 删除软件包（含其全部文件）
+func (g *GitLabAPI) DeletePackage(ctx context.Context, packageID int) error {
+	_, err := g.client.Delete(ctx, g.apiURL("/projects/%d/packages/%d", g.projectID, packageID), &RequestOptions{Headers: g.headers()})
+	if err != nil {
+		return fmt.Errorf("删除软件包失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitLabCommitAction 提交中的单个文件操作
+type GitLabCommitAction struct {
+	Action   string `json:"action"` // create、update、delete
+	FilePath string `json:"file_path"`
+	Content  string `json:"content,omitempty"`
+	Encoding string `json:"encoding,omitempty"` // text（默认）或base64
+}
+
+// GitLabCommit 提交信息
+type GitLabCommit struct {
+	ID      string `json:"id"`
+	ShortID string `json:"short_id"`
+	Title   string `json:"title"`
+	WebURL  string `json:"web_url"`
+}
+
+// GitLabTreeEntry 仓库目录树条目
+type GitLabTreeEntry struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // blob 或 tree
+	Path string `json:"path"`
+}
+
+// GitLabMergeRequest 合并请求信息
+type GitLabMergeRequest struct {
+	ID     int    `json:"id"`
+	IID    int    `json:"iid"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	WebURL string `json:"web_url"`
+}
+
+// WithProject 返回指向另一个项目的客户端副本（例如报表仓库与Issue不在同一项目）
+func (g *GitLabAPI) WithProject(projectID uint) *GitLabAPI {
+	cp := *g
+	cp.projectID = projectID
+	return &cp
+}
+
+// isNotFound 是否为404错误
+func isNotFound(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
+
+// BranchExists 分支是否存在
+func (g *GitLabAPI) BranchExists(ctx context.Context, branch string) (bool, error) {
+	var v map[string]any
+	err := g.getJSON(ctx, g.apiURL("/projects/%d/repository/branches/%s", g.projectID, url.PathEscape(branch)), &v)
+	if isNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("获取分支信息失败: %w", err)
+	}
+	return true, nil
+}
+
+// ListTree 递归列出分支中path下的全部条目（分页获取），分支或目录不存在时返回空
+func (g *GitLabAPI) ListTree(ctx context.Context, branch, path string) ([]GitLabTreeEntry, error) {
+	var entries []GitLabTreeEntry
+	page := "1"
+	for page != "" {
+		u := g.apiURL("/projects/%d/repository/tree?recursive=true&per_page=100&path=%s&ref=%s&page=%s",
+			g.projectID, url.QueryEscape(path), url.QueryEscape(branch), page)
+		resp, err := g.client.Get(ctx, u, &RequestOptions{Headers: g.headers()})
+		if isNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("获取仓库目录失败: %w", err)
+		}
+		var batch []GitLabTreeEntry
+		if err := json.Unmarshal(resp.Body, &batch); err != nil {
+			return nil, fmt.Errorf("解析仓库目录失败: %w", err)
+		}
+		entries = append(entries, batch...)
+		page = resp.Headers.Get("X-Next-Page")
+		if len(batch) == 0 {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// CreateCommit 在分支上创建包含多个文件操作的提交，startBranch不为空时以其为起点新建分支
+func (g *GitLabAPI) CreateCommit(ctx context.Context, branch, startBranch, message string, actions []GitLabCommitAction) (*GitLabCommit, error) {
+	payload := map[string]any{
+		"branch":         branch,
+		"commit_message": message,
+		"actions":        actions,
+	}
+	if startBranch != "" {
+		payload["start_branch"] = startBranch
+	}
+	resp, err := g.client.PostJSON(ctx, g.apiURL("/projects/%d/repository/commits", g.projectID), payload, g.headers())
+	if err != nil {
+		return nil, fmt.Errorf("创建提交失败: %w", err)
+	}
+	var commit GitLabCommit
+	if err := json.Unmarshal(resp.Body, &commit); err != nil {
+		return nil, fmt.Errorf("解析提交响应失败: %w", err)
+	}
+	return &commit, nil
+}
+
+// EnsureMergeRequest 创建合并请求，源分支已有打开的合并请求时直接返回该请求
+func (g *GitLabAPI) EnsureMergeRequest(ctx context.Context, sourceBranch, targetBranch, title, description string) (*GitLabMergeRequest, error) {
+	var existing []GitLabMergeRequest
+	u := g.apiURL("/projects/%d/merge_requests?state=opened&source_branch=%s&target_branch=%s",
+		g.projectID, url.QueryEscape(sourceBranch), url.QueryEscape(targetBranch))
+	if err := g.getJSON(ctx, u, &existing); err != nil {
+		return nil, fmt.Errorf("获取合并请求失败: %w", err)
+	}
+	if len(existing) > 0 {
+		return &existing[0], nil
+	}
+
+	payload := map[string]any{
+		"source_branch": sourceBranch,
+		"target_branch": targetBranch,
+		"title":         title,
+		"description":   description,
+	}
+	resp, err := g.client.PostJSON(ctx, g.apiURL("/projects/%d/merge_requests", g.projectID), payload, g.headers())
+	if err != nil {
+		return nil, fmt.Errorf("创建合并请求失败: %w", err)
+	}
+	var mr GitLabMergeRequest
+	if err := json.Unmarshal(resp.Body, &mr); err != nil {
+		return nil, fmt.Errorf("解析合并请求响应失败: %w", err)
+	}
+	return &mr, nil
+}
+
+// Base64Content 文件内容按base64编码，用于提交二进制文件
+func Base64Content(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// BlobURL 仓库文件的web地址
+func (p *GitLabProject) BlobURL(branch, filePath string) string {
+	return strings.TrimSuffix(p.WebURL, "/") + "/-/blob/" + branch + "/" + filePath
+}
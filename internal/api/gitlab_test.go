@@ -0,0 +1,286 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newFakeGitLab 创建指向本地模拟服务的GitLab客户端
+func newFakeGitLab(t *testing.T, mux *http.ServeMux) *GitLabAPI {
+	t.Helper()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return &GitLabAPI{
+		client:      NewDefaultHTTPClient(),
+		url:         srv.URL,
+		accessToken: "token",
+		projectID:   42,
+		issueIID:    9,
+	}
+}
+
+func writeTestJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func Test_GitLabCommentCreatePermalink(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v4/projects/42/issues/9/notes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeTestJSON(w, map[string]any{"id": 1001, "body": "hello"})
+	})
+	issueCalls := 0
+	mux.HandleFunc("GET /api/v4/projects/42/issues/9", func(w http.ResponseWriter, r *http.Request) {
+		issueCalls++
+		writeTestJSON(w, map[string]any{"iid": 9, "web_url": "https://gitlab.example.com/ops/public/-/issues/9"})
+	})
+	mux.HandleFunc("GET /api/v4/projects/42", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]any{"id": 42, "web_url": "https://gitlab.example.com/ops/public"})
+	})
+	g := newFakeGitLab(t, mux)
+
+	if _, err := g.IssueURL(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	note, err := g.CommentCreate(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.ID != 1001 || note.URL != "https://gitlab.example.com/ops/public/-/issues/9#note_1001" {
+		t.Fatalf("评论信息不符合预期: %+v", note)
+	}
+	if issueCalls != 1 {
+		t.Fatalf("Issue地址应只解析一次，实际请求%d次", issueCalls)
+	}
+
+	project, err := g.Project(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := project.AbsoluteURL("/uploads/abc/a.csv"); got != "https://gitlab.example.com/ops/public/uploads/abc/a.csv" {
+		t.Fatalf("上传文件地址不符合预期: %s", got)
+	}
+}
+
+func Test_GitLabCreateIssueAndLinkPrevious(t *testing.T) {
+	var created GitLabIssueOptions
+	var closed, linked bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v4/projects/42/issues", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&created)
+		w.WriteHeader(http.StatusCreated)
+		writeTestJSON(w, map[string]any{"id": 500, "iid": 10, "web_url": "https://gitlab.example.com/ops/public/-/issues/10"})
+	})
+	mux.HandleFunc("POST /api/v4/projects/42/issues/10/links", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		linked = body["target_issue_iid"] == float64(9)
+		w.WriteHeader(http.StatusCreated)
+		writeTestJSON(w, map[string]any{})
+	})
+	mux.HandleFunc("PUT /api/v4/projects/42/issues/9", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		closed = body["state_event"] == "close"
+		writeTestJSON(w, map[string]any{"iid": 9, "state": "closed"})
+	})
+	g := newFakeGitLab(t, mux)
+
+	issue, err := g.CreateIssue(context.Background(), GitLabIssueOptions{
+		Title:   "MySQL慢日志报表 2026-10-12至2026-10-18",
+		Labels:  "slow-log,weekly",
+		DueDate: "2026-10-25",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.IID != 10 || created.Labels != "slow-log,weekly" || created.DueDate != "2026-10-25" {
+		t.Fatalf("新建Issue不符合预期: %+v %+v", issue, created)
+	}
+
+	current := g.WithIssue(uint(issue.IID))
+	if err := current.LinkIssue(context.Background(), 9); err != nil {
+		t.Fatal(err)
+	}
+	if err := current.CloseIssue(context.Background(), 9); err != nil {
+		t.Fatal(err)
+	}
+	if !linked || !closed {
+		t.Fatalf("上一个Issue未被关联或关闭: linked=%v closed=%v", linked, closed)
+	}
+}
+
+func Test_GitLabCommentUpsert(t *testing.T) {
+	marker := CommentMarker("mysql_slow_log_weekly/2026-10-12_2026-10-18")
+	var updated string
+	created, secondPage := 0, 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/42/issues/9/notes", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Header().Set("X-Next-Page", "2")
+			writeTestJSON(w, []map[string]any{{"id": 3, "body": "其他评论"}})
+		case "2":
+			secondPage++
+			writeTestJSON(w, []map[string]any{{"id": 2, "body": "旧报表\n\n" + marker}})
+		default:
+			t.Errorf("非预期的分页: %s", r.URL.RawQuery)
+		}
+	})
+	mux.HandleFunc("PUT /api/v4/projects/42/issues/9/notes/2", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		updated = body["body"]
+		writeTestJSON(w, map[string]any{"id": 2, "body": updated})
+	})
+	mux.HandleFunc("POST /api/v4/projects/42/issues/9/notes", func(w http.ResponseWriter, r *http.Request) {
+		created++
+		w.WriteHeader(http.StatusCreated)
+		writeTestJSON(w, map[string]any{"id": 4})
+	})
+	mux.HandleFunc("GET /api/v4/projects/42/issues/9", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]any{"iid": 9, "web_url": "https://gitlab.example.com/ops/public/-/issues/9"})
+	})
+	g := newFakeGitLab(t, mux)
+
+	note, err := g.CommentUpsert(context.Background(), marker, "新报表")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 0 || note.ID != 2 || updated != "新报表\n\n"+marker {
+		t.Fatalf("期望原地更新评论，created=%d note=%+v body=%q", created, note, updated)
+	}
+	if note.URL != "https://gitlab.example.com/ops/public/-/issues/9#note_2" {
+		t.Fatalf("评论链接不符合预期: %s", note.URL)
+	}
+
+	// 在第一页找到后不再请求后续分页
+	found, err := g.FindNote(context.Background(), "其他评论")
+	if err != nil || found == nil || found.ID != 3 || secondPage != 1 {
+		t.Fatalf("期望在第一页找到评论且不再翻页: note=%+v secondPage=%d err=%v", found, secondPage, err)
+	}
+}
+
+func Test_GitLabCreateCommit(t *testing.T) {
+	var payload struct {
+		Branch      string               `json:"branch"`
+		StartBranch string               `json:"start_branch"`
+		Actions     []GitLabCommitAction `json:"actions"`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/42/repository/tree", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "1" {
+			w.Header().Set("X-Next-Page", "2")
+			writeTestJSON(w, []GitLabTreeEntry{{Name: "README.md", Type: "blob", Path: "reports/README.md"}})
+			return
+		}
+		writeTestJSON(w, []GitLabTreeEntry{{Name: "2026", Type: "tree", Path: "reports/2026"}})
+	})
+	mux.HandleFunc("POST /api/v4/projects/42/repository/commits", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusCreated)
+		writeTestJSON(w, map[string]any{"id": "abc123", "short_id": "abc"})
+	})
+	mux.HandleFunc("GET /api/v4/projects/42/repository/branches/slow-log-reports", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	g := newFakeGitLab(t, mux)
+
+	entries, err := g.ListTree(context.Background(), "main", "reports")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("目录列表不符合预期: %v %+v", err, entries)
+	}
+	if exists, err := g.BranchExists(context.Background(), "slow-log-reports"); err != nil || exists {
+		t.Fatalf("分支不应存在: %v %v", exists, err)
+	}
+	commit, err := g.CreateCommit(context.Background(), "slow-log-reports", "main", "Add report", []GitLabCommitAction{
+		{Action: "create", FilePath: "reports/2026/a.csv", Content: Base64Content([]byte("id\n")), Encoding: "base64"},
+		{Action: "update", FilePath: "reports/README.md", Content: "# index"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.ID != "abc123" || payload.StartBranch != "main" || len(payload.Actions) != 2 || payload.Actions[0].Content != "aWQK" {
+		t.Fatalf("提交内容不符合预期: %+v", payload)
+	}
+}
+
+func Test_GitLabUpsertWikiPage(t *testing.T) {
+	var method string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/42/wikis/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("slug") != "mysql-slow-log/2026-10-12_2026-10-18" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeTestJSON(w, GitLabWikiPage{Slug: r.PathValue("slug"), Title: "2026-10-12_2026-10-18"})
+	})
+	mux.HandleFunc("PUT /api/v4/projects/42/wikis/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		writeTestJSON(w, GitLabWikiPage{Slug: r.PathValue("slug")})
+	})
+	mux.HandleFunc("POST /api/v4/projects/42/wikis", func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+		writeTestJSON(w, GitLabWikiPage{Slug: WikiSlug(body["title"])})
+	})
+	g := newFakeGitLab(t, mux)
+
+	page, err := g.UpsertWikiPage(context.Background(), "mysql-slow-log/2026-10-12_2026-10-18", "# report")
+	if err != nil || method != http.MethodPut || page.Slug != "mysql-slow-log/2026-10-12_2026-10-18" {
+		t.Fatalf("已存在的页面应更新: method=%s page=%+v err=%v", method, page, err)
+	}
+	page, err = g.UpsertWikiPage(context.Background(), "mysql-slow-log/weekly index", "# index")
+	if err != nil || method != http.MethodPost || page.Slug != "mysql-slow-log/weekly-index" {
+		t.Fatalf("不存在的页面应新建: method=%s page=%+v err=%v", method, page, err)
+	}
+}
+
+func Test_GitLabUploadGenericPackage(t *testing.T) {
+	stored := map[string][]byte{}
+	corrupt := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /api/v4/projects/42/packages/generic/{name}/{version}/{file}", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		stored[r.URL.Path] = body
+		w.WriteHeader(http.StatusCreated)
+		writeTestJSON(w, map[string]string{"message": "201 Created"})
+	})
+	mux.HandleFunc("GET /api/v4/projects/42/packages/generic/{name}/{version}/{file}", func(w http.ResponseWriter, r *http.Request) {
+		body := stored[r.URL.Path]
+		if corrupt {
+			body = append(body, 'x')
+		}
+		w.Write(body)
+	})
+	g := newFakeGitLab(t, mux)
+
+	file := filepath.Join(t.TempDir(), "report_20260101000000.csv")
+	os.WriteFile(file, []byte("id,sql\n1,select 1\n"), 0o644)
+	fileURL, err := g.UploadGenericPackage(context.Background(), "mysql_slow_log_weekly", "2026-10-12_2026-10-18", "report.csv", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(fileURL, "/api/v4/projects/42/packages/generic/mysql_slow_log_weekly/2026-10-12_2026-10-18/report.csv") {
+		t.Fatalf("下载地址不符合预期: %s", fileURL)
+	}
+
+	corrupt = true
+	if _, err := g.UploadGenericPackage(context.Background(), "mysql_slow_log_weekly", "2026-10-12_2026-10-18", "report.csv", file); err == nil {
+		t.Fatal("校验和不一致时应返回错误")
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// GitLabWikiPage Wiki页面
+type GitLabWikiPage struct {
+	Slug    string `json:"slug"`
+	Title   string `json:"title"`
+	Content string `json:"content,omitempty"`
+	Format  string `json:"format,omitempty"`
+}
+
+// WikiSlug 由页面标题生成slug（空格替换为-，/表示目录）
+func WikiSlug(title string) string {
+	return strings.ReplaceAll(strings.TrimSpace(title), " ", "-")
+}
+
+// WikiURL Wiki页面的web地址
+func (p *GitLabProject) WikiURL(slug string) string {
+	return strings.TrimSuffix(p.WebURL, "/") + "/-/wikis/" + slug
+}
+
+// ListWikiPages 列出项目全部Wiki页面（不含内容）
+func (g *GitLabAPI) ListWikiPages(ctx context.Context) ([]GitLabWikiPage, error) {
+	var pages []GitLabWikiPage
+	if err := g.getJSON(ctx, g.apiURL("/projects/%d/wikis", g.projectID), &pages); err != nil {
+		return nil, fmt.Errorf("获取Wiki页面列表失败: %w", err)
+	}
+	return pages, nil
+}
+
+// UpsertWikiPage 创建或更新Wiki页面（Markdown格式）
+func (g *GitLabAPI) UpsertWikiPage(ctx context.Context, title, content string) (*GitLabWikiPage, error) {
+	slug := WikiSlug(title)
+	pageURL := g.apiURL("/projects/%d/wikis/%s", g.projectID, url.PathEscape(slug))
+
+	var existing GitLabWikiPage
+	err := g.getJSON(ctx, pageURL, &existing)
+	if err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("获取Wiki页面失败: %w", err)
+	}
+
+	payload := map[string]string{
+		"title":   title,
+		"content": content,
+		"format":  "markdown",
+	}
+	var resp *Response
+	if err == nil {
+		resp, err = g.putJSON(ctx, pageURL, payload)
+	} else {
+		resp, err = g.client.PostJSON(ctx, g.apiURL("/projects/%d/wikis", g.projectID), payload, g.headers())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("保存Wiki页面失败: %w", err)
+	}
+
+	var page GitLabWikiPage
+	if err := json.Unmarshal(resp.Body, &page); err != nil {
+		return nil, fmt.Errorf("解析Wiki页面响应失败: %w", err)
+	}
+	return &page, nil
+}
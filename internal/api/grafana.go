@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
-	"time"
 )
 
 // GrafanaClient 封装Grafana API操作
@@ -62,24 +61,11 @@ func (g *GrafanaClient) buildURL() string {
 }
 
 // newReqBodyParams 创建请求体参数
-func newReqBodyParams() ReqBodyParams {
+func newReqBodyParams(window TimeWindow) ReqBodyParams {
 	appConf := conf.GetAppConfig()
-
-	// 获取前一天完整的起始和结束时间戳
-	loc, _ := time.LoadLocation("Asia/Shanghai")
-	now := time.Now().In(loc)
-	d := 0 - appConf.Query.LookBackDays
-	if d >= -1 {
-		d = -1
-	}
-	startDay := now.AddDate(0, 0, d)
-	endDay := now.AddDate(0, 0, -1)
-	startMillis := time.Date(startDay.Year(), startDay.Month(), startDay.Day(), 0, 0, 0, 0, loc).UnixMilli()
-	endMillis := time.Date(endDay.Year(), endDay.Month(), endDay.Day(), 23, 59, 59, 0, loc).UnixMilli()
-
 	return ReqBodyParams{
-		sTimeUnix:         startMillis,
-		eTimeUnix:         endMillis,
+		sTimeUnix:         window.Start.UnixMilli(),
+		eTimeUnix:         window.End.UnixMilli(),
 		QueryTimeGtFilter: appConf.Query.QueryTimeThreshold,
 		Interval:          appConf.Query.Interval,
 	}
@@ -96,12 +82,12 @@ func (g *GrafanaClient) buildReqBody(params ReqBodyParams) string {
 	return fmt.Sprintf("%s\n%s\n", header, query)
 }
 
-// GetMySQLSlowQueryData 获取MySQL慢查询数据
-func (g *GrafanaClient) GetMySQLSlowQueryData(ctx context.Context) (*GrafanaResponse, error) {
+// GetMySQLSlowQueryData 获取指定时间窗口内的MySQL慢查询数据
+func (g *GrafanaClient) GetMySQLSlowQueryData(ctx context.Context, window TimeWindow) (*GrafanaResponse, error) {
 	url := g.buildURL()
 
 	// 准备请求体
-	params := newReqBodyParams()
+	params := newReqBodyParams(window)
 	requestBody := g.buildReqBody(params)
 
 	// 设置请求头
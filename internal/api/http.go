@@ -3,11 +3,13 @@ package api
 import (
 	"bytes"
 	"context"
+	"dailyDataPanel/internal/metrics"
 	"encoding/json"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -164,12 +166,16 @@ func (c *HTTPClient) doRequest(ctx context.Context, method, url string, options
 		req.Header.Set("Content-Type", contentType)
 	}
 
-	// 发送请求
+	// 发送请求（记录请求数与耗时指标）
+	start := time.Now()
 	resp, err := c.client.Do(req)
+	metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), req.URL.Host, method)
 	if err != nil {
+		metrics.HTTPRequests.Inc(req.URL.Host, method, "error")
 		return nil, err
 	}
 	defer resp.Body.Close()
+	metrics.HTTPRequests.Inc(req.URL.Host, method, strconv.Itoa(resp.StatusCode))
 
 	// 读取响应体
 	respBody, err := io.ReadAll(resp.Body)
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"dailyDataPanel/internal/conf"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+// OSSCredentials 访问OSS使用的凭据（临时凭据需携带SecurityToken）
+type OSSCredentials struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	SecurityToken   string
+}
+
+// OSSCredentialsProvider 每次请求前获取凭据，便于STS、实例角色等临时凭据自动刷新
+type OSSCredentialsProvider func() (OSSCredentials, error)
+
+// OSSConfig OSS连接参数
+type OSSConfig struct {
+	Endpoint    string // 地域访问域名，如 https://oss-cn-hangzhou.aliyuncs.com
+	Bucket      string
+	Credentials OSSCredentialsProvider
+
+	// PublicEndpoint 签名下载地址使用的域名，为空时与Endpoint相同。
+	// Endpoint为内网域名时需配置公网域名，否则外部无法打开下载链接
+	PublicEndpoint string
+}
+
+// OSSClient 阿里云OSS客户端（V1签名，不依赖SDK）
+type OSSClient struct {
+	client         *HTTPClient
+	endpoint       *url.URL
+	publicEndpoint *url.URL
+	bucket         string
+	creds          OSSCredentialsProvider
+	now            func() time.Time
+}
+
+// OSSPutOptions 上传对象的选项
+type OSSPutOptions struct {
+	ContentType  string
+	StorageClass string            // Standard、IA、Archive等，为空时使用存储桶默认值
+	Tags         map[string]string // 对象标签，可用于按标签匹配的生命周期规则
+}
+
+// OSSEndpoint 按地域生成访问域名，internal为true时使用内网域名（同地域ECS访问免流量费）
+func OSSEndpoint(region string, internal bool) string {
+	host := "oss-" + region
+	if internal {
+		host += "-internal"
+	}
+	return "https://" + host + ".aliyuncs.com"
+}
+
+// AliOSSCredentials 使用与RDS相同的阿里云凭据访问OSS
+func AliOSSCredentials() (OSSCredentialsProvider, error) {
+	cred, err := NewAliCredential(conf.GetAppConfig().Ali)
+	if err != nil {
+		return nil, err
+	}
+	return func() (OSSCredentials, error) {
+		model, err := cred.GetCredential()
+		if err != nil {
+			return OSSCredentials{}, fmt.Errorf("获取阿里云凭据失败: %w", err)
+		}
+		return OSSCredentials{
+			AccessKeyID:     tea.StringValue(model.AccessKeyId),
+			AccessKeySecret: tea.StringValue(model.AccessKeySecret),
+			SecurityToken:   tea.StringValue(model.SecurityToken),
+		}, nil
+	}, nil
+}
+
+// NewOSSClient 创建OSS客户端
+func NewOSSClient(cfg OSSConfig) (*OSSClient, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("OSS未配置ENDPOINT（或REGION）与BUCKET")
+	}
+	if cfg.Credentials == nil {
+		return nil, errors.New("OSS未配置凭据")
+	}
+	endpoint, err := parseOSSEndpoint(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	publicEndpoint := endpoint
+	if cfg.PublicEndpoint != "" {
+		if publicEndpoint, err = parseOSSEndpoint(cfg.PublicEndpoint); err != nil {
+			return nil, err
+		}
+	}
+	return &OSSClient{
+		client:         NewHTTPClient(5 * time.Minute),
+		endpoint:       endpoint,
+		publicEndpoint: publicEndpoint,
+		bucket:         cfg.Bucket,
+		creds:          cfg.Credentials,
+		now:            time.Now,
+	}, nil
+}
+
+// parseOSSEndpoint 解析访问域名，未指定协议时使用https
+func parseOSSEndpoint(endpoint string) (*url.URL, error) {
+	raw := endpoint
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(strings.TrimSuffix(raw, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("OSS ENDPOINT格式错误: %s", endpoint)
+	}
+	return u, nil
+}
+
+// objectURL 对象地址（虚拟主机风格），query为已编码的查询字符串
+func (c *OSSClient) objectURL(endpoint *url.URL, key, query string) string {
+	u := endpoint.Scheme + "://" + c.bucket + "." + endpoint.Host + "/" + s3Escape(key, false)
+	if query != "" {
+		u += "?" + query
+	}
+	return u
+}
+
+// OSSSignature 计算OSS V1签名：
+// base64(hmac-sha1(secret, VERB\nContent-MD5\nContent-Type\nDate\nCanonicalizedOSSHeaders+CanonicalizedResource))
+func OSSSignature(secret, method, contentMD5, contentType, date string, headers map[string]string, resource string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-oss-") {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return strings.ToLower(names[i]) < strings.ToLower(names[j]) })
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(strings.ToLower(name) + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+
+	stringToSign := strings.Join([]string{method, contentMD5, contentType, date, canonical.String() + resource}, "\n")
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// PutObject 上传本地文件（单次上传，最大5GB），携带Content-MD5由服务端校验完整性
+func (c *OSSClient) PutObject(ctx context.Context, key, filePath string, opts OSSPutOptions) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %w", err)
+	}
+	creds, err := c.creds()
+	if err != nil {
+		return err
+	}
+
+	sum := md5.Sum(content)
+	contentMD5 := base64.StdEncoding.EncodeToString(sum[:])
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	date := c.now().UTC().Format(http.TimeFormat)
+	headers := map[string]string{
+		"Content-MD5":  contentMD5,
+		"Content-Type": contentType,
+		"Date":         date,
+	}
+	if opts.StorageClass != "" {
+		headers["x-oss-storage-class"] = opts.StorageClass
+	}
+	if len(opts.Tags) > 0 {
+		tags := url.Values{}
+		for k, v := range opts.Tags {
+			tags.Set(k, v)
+		}
+		headers["x-oss-tagging"] = tags.Encode()
+	}
+	if creds.SecurityToken != "" {
+		headers["x-oss-security-token"] = creds.SecurityToken
+	}
+	resource := "/" + c.bucket + "/" + key
+	signature := OSSSignature(creds.AccessKeySecret, "PUT", contentMD5, contentType, date, headers, resource)
+	headers["Authorization"] = "OSS " + creds.AccessKeyID + ":" + signature
+
+	if _, err := c.client.Put(ctx, c.objectURL(c.endpoint, key, ""), &RequestOptions{Headers: headers, Body: content}); err != nil {
+		return fmt.Errorf("上传OSS对象 %s 失败: %w", key, err)
+	}
+	return nil
+}
+
+// SignURL 生成对象的签名下载地址（使用公网域名）
+func (c *OSSClient) SignURL(key string, expires time.Duration) (string, error) {
+	if expires <= 0 {
+		return "", fmt.Errorf("签名有效期须大于0: %s", expires)
+	}
+	creds, err := c.creds()
+	if err != nil {
+		return "", err
+	}
+	expiresAt := strconv.FormatInt(c.now().Add(expires).Unix(), 10)
+	resource := "/" + c.bucket + "/" + key
+	if creds.SecurityToken != "" {
+		resource += "?security-token=" + creds.SecurityToken
+	}
+	query := url.Values{}
+	query.Set("OSSAccessKeyId", creds.AccessKeyID)
+	query.Set("Expires", expiresAt)
+	query.Set("Signature", OSSSignature(creds.AccessKeySecret, "GET", "", "", expiresAt, nil, resource))
+	if creds.SecurityToken != "" {
+		query.Set("security-token", creds.SecurityToken)
+	}
+	return c.objectURL(c.publicEndpoint, key, query.Encode()), nil
+}
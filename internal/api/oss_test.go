@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 待签名字符串为：
+// PUT\nODBG...NEM=\ntext/html\nThu, 17 Nov 2005 18:49:58 GMT\nx-oss-magic:abracadabra\nx-oss-meta-author:foo@example.com\n/oss-example/nelson
+func Test_OSSSignature(t *testing.T) {
+	got := OSSSignature("OtxrzxIsfpFjA7SwPzILwy8Bw21TLhquhboDYROV", "PUT",
+		"ODBGOERFMDMzQTczRUY3NUE3NzA5QzdFNUYzMDQxNEM=", "text/html", "Thu, 17 Nov 2005 18:49:58 GMT",
+		map[string]string{"X-OSS-Meta-Author": "foo@example.com", "X-OSS-Magic": "abracadabra"},
+		"/oss-example/nelson")
+	if want := "fV5fq7DPwNbrrig7nvUSZIVyruI="; got != want {
+		t.Fatalf("签名不符合预期: got %s, want %s", got, want)
+	}
+}
+
+func Test_OSSPutObjectAndSignURL(t *testing.T) {
+	var gotKey, gotTagging string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sum := md5.Sum(body)
+		if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "OSS AK:") ||
+			r.Header.Get("X-Oss-Security-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		gotKey, gotTagging = r.URL.Path, r.Header.Get("X-Oss-Tagging")
+	}))
+	defer srv.Close()
+
+	c, err := NewOSSClient(OSSConfig{
+		Endpoint:       srv.URL,
+		PublicEndpoint: "oss-cn-hangzhou.aliyuncs.com",
+		Bucket:         "reports",
+		Credentials: func() (OSSCredentials, error) {
+			return OSSCredentials{AccessKeyID: "AK", AccessKeySecret: "SK", SecurityToken: "token"}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 测试服务器不支持虚拟主机风格域名，直接请求服务器地址
+	c.client.client.Transport = rewriteHostTransport(srv.Listener.Addr().String())
+	c.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	file := filepath.Join(t.TempDir(), "a.csv")
+	if err := os.WriteFile(file, []byte("a,b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := OSSPutOptions{ContentType: "text/csv", Tags: map[string]string{"report": "weekly"}}
+	if err := c.PutObject(context.Background(), "slowlog/weekly/a.csv", file, opts); err != nil {
+		t.Fatal(err)
+	}
+	if gotKey != "/slowlog/weekly/a.csv" || gotTagging != "report=weekly" {
+		t.Fatalf("上传请求不符合预期: key=%s tagging=%s", gotKey, gotTagging)
+	}
+
+	signed, err := c.SignURL("slowlog/weekly/a.csv", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"https://reports.oss-cn-hangzhou.aliyuncs.com/slowlog/weekly/a.csv?", "Expires=1700003600", "OSSAccessKeyId=AK", "security-token=token", "Signature="} {
+		if !strings.Contains(signed, want) {
+			t.Fatalf("签名地址缺少 %s: %s", want, signed)
+		}
+	}
+}
+
+// rewriteHostTransport 将请求发往固定地址，保留原始Host
+func rewriteHostTransport(addr string) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		r = r.Clone(r.Context())
+		r.URL.Host = addr
+		return http.DefaultTransport.RoundTrip(r)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy 重试策略，退避时间按指数增长
+type RetryPolicy struct {
+	MaxAttempts    int                  // 最大尝试次数（含首次），小于1时按1处理
+	InitialBackoff time.Duration        // 首次重试前的等待时间
+	MaxBackoff     time.Duration        // 单次等待时间上限，为0则不限制
+	Retryable      func(err error) bool // 判断错误是否可重试，为空时使用IsRetryableHTTPError
+}
+
+// DefaultRetryPolicy 默认重试策略：最多3次，1s起指数退避
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+// Retry 按策略执行fn，直到成功、错误不可重试、次数用尽或ctx结束，返回最后一次的错误
+func Retry(ctx context.Context, policy RetryPolicy, fn func(attempt int) error) error {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryableHTTPError
+	}
+	backoff := policy.InitialBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(attempt); err == nil {
+			return nil
+		}
+		if attempt >= policy.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// IsRetryableHTTPError 网络错误、429与5xx可重试，ctx取消与其他HTTP状态码不重试
+func IsRetryableHTTPError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+	return true
+}
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// S3MinPartSize 分片上传中除最后一片外每片的最小大小
+	S3MinPartSize = 5 << 20
+	// S3DefaultPartSize 默认分片大小，超过该大小的文件使用分片上传
+	S3DefaultPartSize = 16 << 20
+	// S3MaxPresignExpiry 预签名地址的最长有效期
+	S3MaxPresignExpiry = 7 * 24 * time.Hour
+
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3TimeFormat      = "20060102T150405Z"
+)
+
+// S3Config S3兼容对象存储的连接参数
+type S3Config struct {
+	Endpoint        string // 服务地址，如 https://s3.amazonaws.com、http://minio:9000
+	Region          string // 签名使用的区域，默认us-east-1
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // 临时凭证的会话令牌，可选
+	PathStyle       bool   // 使用路径风格地址（endpoint/bucket/key），MinIO等通常需要开启
+	PartSize        int64  // 分片大小，默认S3DefaultPartSize
+}
+
+// S3Client S3兼容对象存储客户端（SigV4签名，不依赖SDK）
+type S3Client struct {
+	client   *HTTPClient
+	cfg      S3Config
+	endpoint *url.URL
+	now      func() time.Time
+}
+
+// S3PutOptions 上传对象的选项
+type S3PutOptions struct {
+	ContentType  string
+	StorageClass string // 如STANDARD、STANDARD_IA、GLACIER，为空时使用存储桶默认值
+}
+
+// NewS3Client 创建S3客户端
+func NewS3Client(cfg S3Config) (*S3Client, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("S3未配置ENDPOINT或BUCKET")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("S3未配置访问密钥")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3 ENDPOINT格式错误: %s", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.PartSize <= 0 {
+		cfg.PartSize = S3DefaultPartSize
+	}
+	if cfg.PartSize < S3MinPartSize {
+		return nil, fmt.Errorf("S3分片大小不能小于%dMB", S3MinPartSize>>20)
+	}
+	return &S3Client{
+		client:   NewHTTPClient(5 * time.Minute),
+		cfg:      cfg,
+		endpoint: endpoint,
+		now:      time.Now,
+	}, nil
+}
+
+// host 对象所在的主机：路径风格为endpoint主机，否则为bucket.endpoint主机
+func (c *S3Client) host() string {
+	if c.cfg.PathStyle {
+		return c.endpoint.Host
+	}
+	return c.cfg.Bucket + "." + c.endpoint.Host
+}
+
+// objectPath 对象的规范路径（已编码），ENDPOINT带路径（如反向代理下的 https://minio.example.com/s3）时保留为前缀
+func (c *S3Client) objectPath(key string) string {
+	p := "/" + s3Escape(strings.TrimPrefix(key, "/"), false)
+	if c.cfg.PathStyle {
+		p = "/" + s3Escape(c.cfg.Bucket, true) + p
+	}
+	if prefix := strings.Trim(c.endpoint.Path, "/"); prefix != "" {
+		p = "/" + s3Escape(prefix, false) + p
+	}
+	return p
+}
+
+// s3Escape 按SigV4规则编码：仅保留未保留字符，encodeSlash为false时保留路径分隔符
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case 'A' <= ch && ch <= 'Z', 'a' <= ch && ch <= 'z', '0' <= ch && ch <= '9',
+			ch == '-', ch == '_', ch == '.', ch == '~':
+			b.WriteByte(ch)
+		case ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery 按键排序并编码的查询字符串
+func canonicalQuery(query map[string]string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, s3Escape(k, true)+"="+s3Escape(query[k], true))
+	}
+	return strings.Join(parts, "&")
+}
+
+// scope 签名范围：日期/区域/s3/aws4_request
+func (c *S3Client) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + c.cfg.Region + "/s3/aws4_request"
+}
+
+// signature 计算SigV4签名，headers的键须为小写
+func (c *S3Client) signature(t time.Time, method, path string, query, headers map[string]string, payloadHash string) (signature, signedHeaders string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders = strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		method, path, canonicalQuery(query), canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		s3Algorithm, t.Format(s3TimeFormat), c.scope(t), sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.cfg.SecretAccessKey), t.Format("20060102"))
+	for _, part := range []string{c.cfg.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, stringToSign)), signedHeaders
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// do 发送签名请求，headers中的x-amz-*与content-type参与签名
+func (c *S3Client) do(ctx context.Context, method, key string, query, headers map[string]string, body []byte) (*Response, error) {
+	t := c.now().UTC()
+	payloadHash := sha256Hex(body)
+	signed := map[string]string{
+		"host":                 c.host(),
+		"x-amz-date":           t.Format(s3TimeFormat),
+		"x-amz-content-sha256": payloadHash,
+	}
+	if c.cfg.SessionToken != "" {
+		signed["x-amz-security-token"] = c.cfg.SessionToken
+	}
+	for name, value := range headers {
+		signed[strings.ToLower(name)] = value
+	}
+	path := c.objectPath(key)
+	signature, signedHeaders := c.signature(t, method, path, query, signed, payloadHash)
+
+	reqHeaders := make(map[string]string, len(signed))
+	for name, value := range signed {
+		if name != "host" {
+			reqHeaders[name] = value
+		}
+	}
+	reqHeaders["Authorization"] = fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, c.cfg.AccessKeyID, c.scope(t), signedHeaders, signature)
+
+	u := c.endpoint.Scheme + "://" + c.host() + path
+	if q := canonicalQuery(query); q != "" {
+		u += "?" + q
+	}
+	return c.client.doRequest(ctx, method, u, &RequestOptions{Headers: reqHeaders, Body: body})
+}
+
+// headers 上传对象时的请求头
+func (opts S3PutOptions) headers() map[string]string {
+	headers := make(map[string]string)
+	if opts.ContentType != "" {
+		headers["content-type"] = opts.ContentType
+	}
+	if opts.StorageClass != "" {
+		headers["x-amz-storage-class"] = opts.StorageClass
+	}
+	return headers
+}
+
+// PutObject 上传本地文件，超过分片大小时使用分片上传
+func (c *S3Client) PutObject(ctx context.Context, key, filePath string, opts S3PutOptions) error {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %w", err)
+	}
+	if info.Size() > c.cfg.PartSize {
+		return c.multipartUpload(ctx, key, filePath, opts)
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %w", err)
+	}
+	if _, err := c.do(ctx, "PUT", key, nil, opts.headers(), content); err != nil {
+		return fmt.Errorf("上传对象 %s 失败: %w", key, err)
+	}
+	return nil
+}
+
+type s3InitiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletedPart `xml:"Part"`
+}
+
+// multipartUpload 分片上传，任一分片失败时中止上传以释放已上传的分片
+func (c *S3Client) multipartUpload(ctx context.Context, key, filePath string, opts S3PutOptions) error {
+	resp, err := c.do(ctx, "POST", key, map[string]string{"uploads": ""}, opts.headers(), nil)
+	if err != nil {
+		return fmt.Errorf("创建分片上传失败: %w", err)
+	}
+	var initiated s3InitiateMultipartUploadResult
+	if err := xml.Unmarshal(resp.Body, &initiated); err != nil || initiated.UploadID == "" {
+		return fmt.Errorf("解析分片上传响应失败: %s", resp.Body)
+	}
+	uploadID := initiated.UploadID
+
+	parts, err := c.uploadParts(ctx, key, uploadID, filePath)
+	if err == nil {
+		var body []byte
+		body, err = xml.Marshal(s3CompleteMultipartUpload{Parts: parts})
+		if err == nil {
+			resp, err = c.do(ctx, "POST", key, map[string]string{"uploadId": uploadID}, nil, body)
+			// 合并分片可能在返回200后才失败，错误信息位于响应体中
+			if err == nil && strings.Contains(string(resp.Body), "<Error>") {
+				err = fmt.Errorf("合并分片失败: %s", resp.Body)
+			}
+		}
+	}
+	if err != nil {
+		// 使用独立的上下文中止，避免上传因超时取消后无法清理
+		abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_, abortErr := c.do(abortCtx, "DELETE", key, map[string]string{"uploadId": uploadID}, nil, nil)
+		return errors.Join(fmt.Errorf("分片上传对象 %s 失败: %w", key, err), abortErr)
+	}
+	return nil
+}
+
+// uploadParts 依次上传各分片，返回分片编号与ETag
+func (c *S3Client) uploadParts(ctx context.Context, key, uploadID, filePath string) ([]s3CompletedPart, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	defer f.Close()
+
+	var parts []s3CompletedPart
+	buf := make([]byte, c.cfg.PartSize)
+	for number := 1; ; number++ {
+		n, err := io.ReadFull(f, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("读取文件失败: %w", err)
+		}
+		query := map[string]string{"partNumber": strconv.Itoa(number), "uploadId": uploadID}
+		resp, err := c.do(ctx, "PUT", key, query, nil, buf[:n])
+		if err != nil {
+			return nil, fmt.Errorf("上传第%d个分片失败: %w", number, err)
+		}
+		etag := resp.Headers.Get("ETag")
+		if etag == "" {
+			return nil, fmt.Errorf("第%d个分片的响应缺少ETag", number)
+		}
+		parts = append(parts, s3CompletedPart{PartNumber: number, ETag: etag})
+		if n < len(buf) {
+			break
+		}
+	}
+	return parts, nil
+}
+
+// PresignGetObject 生成对象的预签名下载地址，expires不超过7天
+func (c *S3Client) PresignGetObject(key string, expires time.Duration) (string, error) {
+	if expires <= 0 || expires > S3MaxPresignExpiry {
+		return "", fmt.Errorf("预签名有效期须在1秒至7天之间: %s", expires)
+	}
+	t := c.now().UTC()
+	query := map[string]string{
+		"X-Amz-Algorithm":     s3Algorithm,
+		"X-Amz-Credential":    c.cfg.AccessKeyID + "/" + c.scope(t),
+		"X-Amz-Date":          t.Format(s3TimeFormat),
+		"X-Amz-Expires":       strconv.Itoa(int(expires.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if c.cfg.SessionToken != "" {
+		query["X-Amz-Security-Token"] = c.cfg.SessionToken
+	}
+	path := c.objectPath(key)
+	signature, _ := c.signature(t, "GET", path, query, map[string]string{"host": c.host()}, s3UnsignedPayload)
+	return c.endpoint.Scheme + "://" + c.host() + path + "?" + canonicalQuery(query) + "&X-Amz-Signature=" + signature, nil
+}
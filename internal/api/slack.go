@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// SlackAPI 封装Slack Incoming Webhook操作
+type SlackAPI struct {
+	client *HTTPClient
+	url    string
+}
+
+func NewSlackAPI(webhookURL string) *SlackAPI {
+	return &SlackAPI{
+		client: NewDefaultHTTPClient(),
+		url:    webhookURL,
+	}
+}
+
+// Call 发送消息(mrkdwn形式)
+func (s *SlackAPI) Call(ctx context.Context, text string) error {
+	payload := map[string]any{
+		"text":   text,
+		"mrkdwn": true,
+	}
+
+	_, err := s.client.PostJSON(ctx, s.url, payload, nil)
+	if err != nil {
+		return fmt.Errorf("发送Slack消息失败: %w", err)
+	}
+
+	return nil
+}
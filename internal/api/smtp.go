@@ -0,0 +1,215 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP连接加密方式
+const (
+	SMTPStartTLS = "starttls" // 明文连接后升级为TLS（默认，通常为587端口）
+	SMTPTLS      = "tls"      // 隐式TLS（通常为465端口）
+	SMTPNone     = "none"     // 不加密，仅用于内网中继或测试
+)
+
+// SMTPClient 封装SMTP邮件发送
+type SMTPClient struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	tlsMode  string
+	timeout  time.Duration
+}
+
+// EmailMessage 邮件内容
+type EmailMessage struct {
+	Subject     string
+	To          []string
+	Cc          []string
+	HTMLBody    string
+	Attachments []EmailAttachment
+}
+
+// EmailAttachment 邮件附件
+type EmailAttachment struct {
+	FileName string
+	Data     []byte
+}
+
+// NewSMTPClient 创建SMTP客户端，username为空时不认证
+func NewSMTPClient(host string, port int, username, password, from, tlsMode string) *SMTPClient {
+	if tlsMode == "" {
+		tlsMode = SMTPStartTLS
+	}
+	return &SMTPClient{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+		tlsMode:  tlsMode,
+		timeout:  30 * time.Second,
+	}
+}
+
+// Send 发送邮件
+func (c *SMTPClient) Send(ctx context.Context, msg *EmailMessage) error {
+	if len(msg.To) == 0 {
+		return errors.New("邮件收件人为空")
+	}
+	data, err := c.buildMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("连接SMTP服务器失败: %w", err)
+	}
+	defer client.Close()
+
+	if c.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.username, c.password, c.host)); err != nil {
+			return fmt.Errorf("SMTP认证失败: %w", err)
+		}
+	}
+	if err := client.Mail(c.from); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM失败: %w", err)
+	}
+	for _, rcpt := range append(append([]string{}, msg.To...), msg.Cc...) {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s 失败: %w", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA失败: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("写入邮件内容失败: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return client.Quit()
+}
+
+// dial 按加密方式建立连接
+func (c *SMTPClient) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(c.host, strconv.Itoa(c.port))
+	dialer := &net.Dialer{Timeout: c.timeout}
+	tlsConfig := &tls.Config{ServerName: c.host}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if c.tlsMode == SMTPTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if c.tlsMode == SMTPStartTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS失败: %w", err)
+		}
+	}
+	return client, nil
+}
+
+// buildMessage 生成MIME邮件：HTML正文+附件
+func (c *SMTPClient) buildMessage(msg *EmailMessage) ([]byte, error) {
+	boundary, err := randomBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&buf, "%s: %s\r\n", k, v) }
+	header("From", c.from)
+	header("To", strings.Join(msg.To, ", "))
+	if len(msg.Cc) > 0 {
+		header("Cc", strings.Join(msg.Cc, ", "))
+	}
+	header("Subject", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", fmt.Sprintf(`multipart/mixed; boundary="%s"`, boundary))
+	buf.WriteString("\r\n")
+
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	header("Content-Type", `text/html; charset="UTF-8"`)
+	header("Content-Transfer-Encoding", "base64")
+	buf.WriteString("\r\n")
+	writeBase64Lines(&buf, []byte(msg.HTMLBody))
+
+	for _, att := range msg.Attachments {
+		name := mime.BEncoding.Encode("UTF-8", filepath.Base(att.FileName))
+		contentType := mime.TypeByExtension(filepath.Ext(att.FileName))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		header("Content-Type", fmt.Sprintf(`%s; name="%s"`, contentType, name))
+		header("Content-Transfer-Encoding", "base64")
+		header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+		buf.WriteString("\r\n")
+		writeBase64Lines(&buf, att.Data)
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
+
+// EncodedAttachmentSize 附件经base64编码（每行76个字符加CRLF）后在邮件中占用的字节数
+func EncodedAttachmentSize(n int) int64 {
+	encoded := int64(base64.StdEncoding.EncodedLen(n))
+	return encoded + max((encoded+75)/76, 1)*2
+}
+
+// writeBase64Lines 写入base64内容，每行76个字符
+func writeBase64Lines(buf *bytes.Buffer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+}
+
+func randomBoundary() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成邮件分隔符失败: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
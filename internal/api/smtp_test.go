@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// smtpSink 本地SMTP接收端，记录收件人与邮件内容
+type smtpSink struct {
+	rcpts []string
+	data  string
+	auth  string
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 sink ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		switch upper := strings.ToUpper(cmd); {
+		case strings.HasPrefix(upper, "EHLO"):
+			reply("250-sink")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(upper, "AUTH"):
+			s.auth = cmd
+			reply("235 ok")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			s.rcpts = append(s.rcpts, strings.Trim(cmd[len("RCPT TO:"):], "<>"))
+			reply("250 ok")
+		case upper == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.data = data.String()
+			reply("250 queued")
+		case upper == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func Test_SMTPClientSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	sink := &smtpSink{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if conn, err := ln.Accept(); err == nil {
+			sink.serve(conn)
+		}
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	client := NewSMTPClient("127.0.0.1", port, "user", "pass", "report@example.com", SMTPNone)
+	err = client.Send(context.Background(), &EmailMessage{
+		Subject:     "MySQL慢查询数据报表",
+		To:          []string{"a@example.com", "b@example.com"},
+		Cc:          []string{"c@example.com"},
+		HTMLBody:    "<h3>hello</h3>",
+		Attachments: []EmailAttachment{{FileName: "report.csv", Data: []byte("id,sql\n")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	if strings.Join(sink.rcpts, ",") != "a@example.com,b@example.com,c@example.com" {
+		t.Fatalf("收件人不符合预期: %v", sink.rcpts)
+	}
+	if !strings.HasPrefix(sink.auth, "AUTH PLAIN") {
+		t.Fatalf("未进行认证: %q", sink.auth)
+	}
+	for _, want := range []string{"Cc: c@example.com", "Subject: =?UTF-8?b?", "text/html", `filename="report.csv"`} {
+		if !strings.Contains(sink.data, want) {
+			t.Fatalf("邮件内容缺少 %q:\n%s", want, sink.data)
+		}
+	}
+}
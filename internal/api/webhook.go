@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DefaultSignatureHeader 通用Webhook默认签名请求头
+const DefaultSignatureHeader = "X-DataPanel-Signature"
+
+// WebhookTimestampHeader 签名时间戳请求头（Unix秒），接收方应拒绝时间戳偏差过大的请求以防重放
+const WebhookTimestampHeader = "X-DataPanel-Timestamp"
+
+// WebhookAPI 通用出站Webhook，以JSON发送并对时间戳与请求体做HMAC-SHA256签名
+type WebhookAPI struct {
+	client          *HTTPClient
+	url             string
+	secret          string
+	signatureHeader string
+	retry           RetryPolicy
+}
+
+// NewWebhookAPI 创建通用Webhook客户端，secret为空时不签名
+func NewWebhookAPI(webhookURL, secret, signatureHeader string, retry RetryPolicy) *WebhookAPI {
+	if signatureHeader == "" {
+		signatureHeader = DefaultSignatureHeader
+	}
+	return &WebhookAPI{
+		client:          NewDefaultHTTPClient(),
+		url:             webhookURL,
+		secret:          secret,
+		signatureHeader: signatureHeader,
+		retry:           retry,
+	}
+}
+
+// WebhookSignature 计算签名："sha256=" + hex(HmacSHA256(secret, timestamp + "." + body))，
+// timestamp为X-DataPanel-Timestamp请求头的值
+func WebhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send 发送JSON文档，网络错误、429与5xx按重试策略重试，每次重试使用新的时间戳重新签名
+func (w *WebhookAPI) Send(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化Webhook消息失败: %w", err)
+	}
+
+	err = Retry(ctx, w.retry, func(attempt int) error {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers := map[string]string{
+			"X-DataPanel-Delivery-Attempt": strconv.Itoa(attempt),
+			WebhookTimestampHeader:         timestamp,
+		}
+		if w.secret != "" {
+			headers[w.signatureHeader] = WebhookSignature(w.secret, timestamp, body)
+		}
+		_, err := w.client.PostJSON(ctx, w.url, json.RawMessage(body), headers)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("发送Webhook消息失败: %w", err)
+	}
+	return nil
+}
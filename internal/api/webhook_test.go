@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_WebhookSendRetrySigned(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(WebhookTimestampHeader) == "" {
+			t.Errorf("缺少时间戳请求头")
+		}
+		if got := r.Header.Get("X-Signature"); got != WebhookSignature("secret", r.Header.Get(WebhookTimestampHeader), body) {
+			t.Errorf("签名不符合预期: %s", got)
+		}
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	retry := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	wh := NewWebhookAPI(srv.URL, "secret", "X-Signature", retry)
+	if err := wh.Send(context.Background(), map[string]any{"version": 1, "run_id": "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Fatalf("期望重试至第3次成功，实际尝试 %d 次", attempts)
+	}
+
+	// 4xx（429除外）不重试
+	attempts = 0
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	if err := wh.Send(context.Background(), map[string]any{}); err == nil || attempts != 1 {
+		t.Fatalf("期望400时不重试，err=%v attempts=%d", err, attempts)
+	}
+}
@@ -2,41 +2,397 @@ package api
 
 import (
 	"context"
+	"crypto/md5"
 	"dailyDataPanel/internal/conf"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
 )
 
+// 企业微信群机器人的文件大小限制
+const (
+	WeixinMinFileSize  = 5        // 文件需大于5字节
+	WeixinMaxFileSize  = 20 << 20 // 文件不超过20MB
+	WeixinMaxImageSize = 2 << 20  // 图片（base64编码前）不超过2MB
+
+	WeixinMaxMarkdownBytes = 4096 // markdown内容不超过4096字节
+	WeixinMaxTextBytes     = 2048 // text内容不超过2048字节
+	WeixinMaxNewsDescBytes = 512  // 图文消息描述不超过512字节
+	WeixinMaxSubTitleRunes = 112  // 模板卡片二级文本不超过112个字
+)
+
+// WeixinRobotError 企业微信接口返回的业务错误（HTTP状态码为200）
+type WeixinRobotError struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func (e *WeixinRobotError) Error() string {
+	return fmt.Sprintf("企业微信接口错误: errcode=%d, errmsg=%s", e.ErrCode, e.ErrMsg)
+}
+
+// WeixinMentions 消息中需要提醒的成员
+type WeixinMentions struct {
+	UserIDs []string // 成员userid，"@all"表示所有人
+	Mobiles []string // 成员手机号
+}
+
 // WeixinRobotAPI 封装微信机器人API操作
 type WeixinRobotAPI struct {
 	client *HTTPClient
 	url    string
 }
 
+// WeixinNewsArticle 图文消息中的文章
+type WeixinNewsArticle struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url"`
+	PicURL      string `json:"picurl,omitempty"`
+}
+
+// WeixinTemplateCard 模板卡片消息（文本通知模版）
+type WeixinTemplateCard struct {
+	CardType              string                 `json:"card_type"` // text_notice
+	Source                *WeixinCardSource      `json:"source,omitempty"`
+	MainTitle             WeixinCardTitle        `json:"main_title"`
+	EmphasisContent       *WeixinCardTitle       `json:"emphasis_content,omitempty"`
+	SubTitleText          string                 `json:"sub_title_text,omitempty"`
+	HorizontalContentList []WeixinCardHorizontal `json:"horizontal_content_list,omitempty"`
+	JumpList              []WeixinCardJump       `json:"jump_list,omitempty"`
+	CardAction            WeixinCardAction       `json:"card_action"`
+}
+
+// WeixinCardSource 卡片来源
+type WeixinCardSource struct {
+	IconURL   string `json:"icon_url,omitempty"`
+	Desc      string `json:"desc,omitempty"`
+	DescColor int    `json:"desc_color,omitempty"`
+}
+
+// WeixinCardTitle 卡片标题
+type WeixinCardTitle struct {
+	Title string `json:"title,omitempty"`
+	Desc  string `json:"desc,omitempty"`
+}
+
+// WeixinCardHorizontal 卡片二级标题+文本列表项
+type WeixinCardHorizontal struct {
+	KeyName string `json:"keyname"`
+	Value   string `json:"value,omitempty"`
+	Type    int    `json:"type,omitempty"` // 1: 跳转url
+	URL     string `json:"url,omitempty"`
+}
+
+// WeixinCardJump 卡片跳转指引
+type WeixinCardJump struct {
+	Type  int    `json:"type"` // 1: 跳转url
+	Title string `json:"title"`
+	URL   string `json:"url,omitempty"`
+}
+
+// WeixinCardAction 卡片整体点击跳转
+type WeixinCardAction struct {
+	Type int    `json:"type"` // 1: 跳转url
+	URL  string `json:"url,omitempty"`
+}
+
 func NewWeixinRobotAPI() *WeixinRobotAPI {
 	appConf := conf.GetAppConfig()
+	return NewWeixinRobotAPIWithURL(appConf.WeixinRobot.WebhookURL)
+}
+
+// NewWeixinRobotAPIWithURL 使用指定Webhook地址创建（用于配置多个群机器人）
+func NewWeixinRobotAPIWithURL(webhookURL string) *WeixinRobotAPI {
 	return &WeixinRobotAPI{
 		client: NewDefaultHTTPClient(),
-		url:    appConf.WeixinRobot.WebhookURL,
+		url:    webhookURL,
 	}
 }
 
-// Call 发送消息(Markdown形式)
+// send 发送消息
+func (wx *WeixinRobotAPI) send(ctx context.Context, payload any) error {
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	resp, err := wx.client.PostJSON(ctx, wx.url, payload, headers)
+	if err != nil {
+		return err
+	}
+	return parseWeixinResponse(resp.Body, nil)
+}
+
+// parseWeixinResponse 解析errcode/errmsg，v不为空时同时解析其余字段
+func parseWeixinResponse(body []byte, v any) error {
+	var result WeixinRobotError
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("解析企业微信响应失败: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return &result
+	}
+	if v != nil {
+		if err := json.Unmarshal(body, v); err != nil {
+			return fmt.Errorf("解析企业微信响应失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Call 发送消息(Markdown形式)，超过4096字节时按行拆分为多条发送
 func (wx *WeixinRobotAPI) Call(ctx context.Context, mdMsg string) error {
+	return wx.CallWithMentions(ctx, mdMsg, WeixinMentions{})
+}
+
+// CallWithMentions 发送Markdown消息并提醒指定成员。
+// markdown消息仅支持按userid提醒（<@userid>，追加在最后一条消息末尾）；
+// 按手机号提醒或提醒所有人时，另发一条text消息
+func (wx *WeixinRobotAPI) CallWithMentions(ctx context.Context, mdMsg string, mentions WeixinMentions) error {
+	parts := mentions.MarkdownParts(mdMsg)
+	for i, part := range parts {
+		if err := wx.SendMarkdown(ctx, part); err != nil {
+			return fmt.Errorf("第%d/%d条: %w", i+1, len(parts), err)
+		}
+	}
+	return wx.SendMentionText(ctx, WeixinMentionContent, mentions, true)
+}
+
+// splitMentions 拆分提醒对象：markdown消息仅支持按userid提醒，"@all"需通过text消息提醒
+func (m WeixinMentions) splitMentions() (mdMentions, textMentions []string) {
+	for _, id := range m.UserIDs {
+		if id == "@all" {
+			textMentions = append(textMentions, id)
+			continue
+		}
+		mdMentions = append(mdMentions, "<@"+id+">")
+	}
+	return mdMentions, textMentions
+}
+
+// MarkdownParts 按userid的提醒追加在消息末尾，超过4096字节时按行拆分为多条
+func (m WeixinMentions) MarkdownParts(mdMsg string) []string {
+	if mdMentions, _ := m.splitMentions(); len(mdMentions) > 0 {
+		mdMsg = strings.TrimRight(mdMsg, "\n") + "\n" + strings.Join(mdMentions, " ")
+	}
+	return splitByLines(mdMsg, WeixinMaxMarkdownBytes)
+}
+
+// SendMarkdown 发送单条Markdown消息（不拆分）
+func (wx *WeixinRobotAPI) SendMarkdown(ctx context.Context, content string) error {
 	payload := map[string]any{
 		"msgtype": "markdown",
 		"markdown": map[string]string{
-			"content": mdMsg,
+			"content": content,
 		},
 	}
+	if err := wx.send(ctx, payload); err != nil {
+		return fmt.Errorf("发送微信机器人消息失败: %w", err)
+	}
+	return nil
+}
 
-	headers := map[string]string{
-		"Content-Type": "application/json",
+// WeixinMentionContent 单独发送的提醒消息内容
+const WeixinMentionContent = "请关注以上报表"
+
+// SendMentionText 另发一条text消息提醒成员，无需提醒时不发送。
+// inlined为true时按userid的提醒已追加在markdown消息末尾（见MarkdownParts），只需提醒所有人与手机号；
+// 图文、卡片等不支持提醒的消息传false，全部提醒对象都通过text消息提醒
+func (wx *WeixinRobotAPI) SendMentionText(ctx context.Context, content string, mentions WeixinMentions, inlined bool) error {
+	textMentions := mentions.UserIDs
+	if inlined {
+		_, textMentions = mentions.splitMentions()
+	}
+	if len(textMentions) == 0 && len(mentions.Mobiles) == 0 {
+		return nil
 	}
+	return wx.SendText(ctx, content, textMentions, mentions.Mobiles)
+}
 
-	_, err := wx.client.PostJSON(ctx, wx.url, payload, headers)
+// SendText 发送文本消息，mentionedList为userid列表，mobileList为手机号列表（均可包含"@all"）
+func (wx *WeixinRobotAPI) SendText(ctx context.Context, content string, mentionedList, mobileList []string) error {
+	if len(content) > WeixinMaxTextBytes {
+		return fmt.Errorf("文本消息长度为 %d 字节，企业微信要求不超过%d字节", len(content), WeixinMaxTextBytes)
+	}
+	text := map[string]any{
+		"content": content,
+	}
+	if len(mentionedList) > 0 {
+		text["mentioned_list"] = mentionedList
+	}
+	if len(mobileList) > 0 {
+		text["mentioned_mobile_list"] = mobileList
+	}
+	payload := map[string]any{
+		"msgtype": "text",
+		"text":    text,
+	}
+	if err := wx.send(ctx, payload); err != nil {
+		return fmt.Errorf("发送微信机器人文本消息失败: %w", err)
+	}
+	return nil
+}
+
+// splitByLines 按行将内容拆分为不超过limit字节的多段，单行超长时按字符截断
+func splitByLines(content string, limit int) []string {
+	if len(content) <= limit {
+		return []string{content}
+	}
+	var (
+		parts []string
+		cur   strings.Builder
+	)
+	flush := func() {
+		if cur.Len() > 0 {
+			parts = append(parts, strings.TrimRight(cur.String(), "\n"))
+			cur.Reset()
+		}
+	}
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if cur.Len()+len(line) > limit {
+			flush()
+		}
+		for len(line) > limit {
+			cut := limit
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			parts = append(parts, line[:cut])
+			line = line[cut:]
+		}
+		cur.WriteString(line)
+	}
+	flush()
+	return parts
+}
+
+// uploadMediaURL 由Webhook发送地址推导文件上传地址
+func (wx *WeixinRobotAPI) uploadMediaURL(mediaType string) (string, error) {
+	u, err := url.Parse(wx.url)
 	if err != nil {
-		return fmt.Errorf("发送微信机器人消息失败: %w", err)
+		return "", fmt.Errorf("解析Webhook地址失败: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/send") + "/upload_media"
+	q := u.Query()
+	q.Set("type", mediaType)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// UploadMedia 上传文件到企业微信（3天内有效），返回media_id
+func (wx *WeixinRobotAPI) UploadMedia(ctx context.Context, filePath string) (string, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", fmt.Errorf("读取文件信息失败: %w", err)
+	}
+	if info.Size() <= WeixinMinFileSize || info.Size() > WeixinMaxFileSize {
+		return "", fmt.Errorf("文件 %s 大小为 %d 字节，企业微信要求大于5字节且不超过20MB", filepath.Base(filePath), info.Size())
+	}
+
+	uploadURL, err := wx.uploadMediaURL("file")
+	if err != nil {
+		return "", err
+	}
+	files := []FileField{
+		{
+			FieldName: "media",
+			FilePath:  filePath,
+			FileName:  filepath.Base(filePath),
+		},
+	}
+	resp, err := wx.client.PostMultipart(ctx, uploadURL, nil, files, nil)
+	if err != nil {
+		return "", fmt.Errorf("上传文件到企业微信失败: %w", err)
+	}
+
+	uploadResponse := struct {
+		MediaID string `json:"media_id"`
+	}{}
+	if err := parseWeixinResponse(resp.Body, &uploadResponse); err != nil {
+		return "", fmt.Errorf("上传文件到企业微信失败: %w", err)
+	}
+	if uploadResponse.MediaID == "" {
+		return "", errors.New("上传文件到企业微信失败: 响应中缺少media_id")
+	}
+	return uploadResponse.MediaID, nil
+}
+
+// SendFile 上传并发送文件消息
+func (wx *WeixinRobotAPI) SendFile(ctx context.Context, filePath string) error {
+	mediaID, err := wx.UploadMedia(ctx, filePath)
+	if err != nil {
+		return err
+	}
+	payload := map[string]any{
+		"msgtype": "file",
+		"file": map[string]string{
+			"media_id": mediaID,
+		},
+	}
+	if err := wx.send(ctx, payload); err != nil {
+		return fmt.Errorf("发送微信机器人文件消息失败: %w", err)
+	}
+	return nil
+}
+
+// SendImage 发送图片消息（仅支持JPG、PNG）
+func (wx *WeixinRobotAPI) SendImage(ctx context.Context, image []byte) error {
+	if len(image) == 0 || len(image) > WeixinMaxImageSize {
+		return fmt.Errorf("图片大小为 %d 字节，企业微信要求不超过2MB", len(image))
+	}
+	if ct := http.DetectContentType(image); ct != "image/png" && ct != "image/jpeg" {
+		return fmt.Errorf("企业微信图片消息仅支持JPG、PNG格式，实际为 %s", ct)
+	}
+	sum := md5.Sum(image)
+	payload := map[string]any{
+		"msgtype": "image",
+		"image": map[string]string{
+			"base64": base64.StdEncoding.EncodeToString(image),
+			"md5":    hex.EncodeToString(sum[:]),
+		},
 	}
+	if err := wx.send(ctx, payload); err != nil {
+		return fmt.Errorf("发送微信机器人图片消息失败: %w", err)
+	}
+	return nil
+}
 
+// SendNews 发送图文消息（1~8篇文章）
+func (wx *WeixinRobotAPI) SendNews(ctx context.Context, articles []WeixinNewsArticle) error {
+	if len(articles) == 0 || len(articles) > 8 {
+		return fmt.Errorf("图文消息需包含1~8篇文章，实际为 %d 篇", len(articles))
+	}
+	payload := map[string]any{
+		"msgtype": "news",
+		"news": map[string]any{
+			"articles": articles,
+		},
+	}
+	if err := wx.send(ctx, payload); err != nil {
+		return fmt.Errorf("发送微信机器人图文消息失败: %w", err)
+	}
+	return nil
+}
+
+// SendTemplateCard 发送模板卡片消息
+func (wx *WeixinRobotAPI) SendTemplateCard(ctx context.Context, card *WeixinTemplateCard) error {
+	if card.CardType == "" {
+		card.CardType = "text_notice"
+	}
+	payload := map[string]any{
+		"msgtype":       "template_card",
+		"template_card": card,
+	}
+	if err := wx.send(ctx, payload); err != nil {
+		return fmt.Errorf("发送微信机器人模板卡片消息失败: %w", err)
+	}
 	return nil
 }
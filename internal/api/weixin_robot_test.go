@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func Test_WeixinRobotSendFile(t *testing.T) {
+	var msgTypes []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /cgi-bin/webhook/upload_media", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "k" || r.URL.Query().Get("type") != "file" {
+			t.Errorf("上传地址不符合预期: %s", r.URL)
+		}
+		if _, header, err := r.FormFile("media"); err != nil || header.Filename != "report.csv" {
+			t.Errorf("上传文件字段不符合预期: %v", err)
+		}
+		writeTestJSON(w, map[string]any{"errcode": 0, "errmsg": "ok", "type": "file", "media_id": "m-1"})
+	})
+	mux.HandleFunc("POST /cgi-bin/webhook/send", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			MsgType string `json:"msgtype"`
+			File    struct {
+				MediaID string `json:"media_id"`
+			} `json:"file"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload.MsgType == "file" && payload.File.MediaID != "m-1" {
+			t.Errorf("media_id不符合预期: %s", payload.File.MediaID)
+		}
+		msgTypes = append(msgTypes, payload.MsgType)
+		writeTestJSON(w, map[string]any{"errcode": 0, "errmsg": "ok"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	wx := NewWeixinRobotAPIWithURL(srv.URL + "/cgi-bin/webhook/send?key=k")
+
+	file := filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(file, []byte("id,sql\n1,select 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := wx.SendFile(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+
+	// 非PNG/JPG图片与超过限制的文件在发送前被拒绝
+	if err := wx.SendImage(context.Background(), []byte("not an image")); err == nil {
+		t.Fatal("非图片内容应返回错误")
+	}
+	tiny := filepath.Join(t.TempDir(), "tiny.csv")
+	os.WriteFile(tiny, []byte("a"), 0o644)
+	if err := wx.SendFile(context.Background(), tiny); err == nil {
+		t.Fatal("小于5字节的文件应返回错误")
+	}
+	if len(msgTypes) != 1 || msgTypes[0] != "file" {
+		t.Fatalf("发送的消息不符合预期: %v", msgTypes)
+	}
+}
+
+func Test_WeixinRobotSplitAndMentions(t *testing.T) {
+	var payloads []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+		if payload["msgtype"] == "markdown" {
+			content := payload["markdown"].(map[string]any)["content"].(string)
+			if len(content) > WeixinMaxMarkdownBytes {
+				writeTestJSON(w, map[string]any{"errcode": 40058, "errmsg": "markdown.content exceed max length"})
+				return
+			}
+		}
+		writeTestJSON(w, map[string]any{"errcode": 0, "errmsg": "ok"})
+	}))
+	defer srv.Close()
+	wx := NewWeixinRobotAPIWithURL(srv.URL)
+
+	line := strings.Repeat("慢", 100) + "\n" // 301字节
+	msg := strings.Repeat(line, 30)
+	err := wx.CallWithMentions(context.Background(), msg, WeixinMentions{UserIDs: []string{"zhangsan"}, Mobiles: []string{"13800000000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 4 {
+		t.Fatalf("期望发送3条markdown和1条text，实际 %d 条", len(payloads))
+	}
+	last := payloads[2]["markdown"].(map[string]any)["content"].(string)
+	if !strings.HasSuffix(last, "<@zhangsan>") || !strings.HasPrefix(last, "慢") {
+		t.Fatalf("最后一条markdown不符合预期: %q", last)
+	}
+	if mobiles := payloads[3]["text"].(map[string]any)["mentioned_mobile_list"].([]any); mobiles[0] != "13800000000" {
+		t.Fatalf("手机号提醒不符合预期: %v", mobiles)
+	}
+
+	// 单行超长时按字符拆分，且不截断多字节字符
+	for _, part := range splitByLines(strings.Repeat("慢", 2000), WeixinMaxMarkdownBytes) {
+		if len(part) > WeixinMaxMarkdownBytes || !utf8.ValidString(part) {
+			t.Fatalf("拆分结果不符合预期: %d 字节", len(part))
+		}
+	}
+
+	// errcode非0时返回错误
+	payloads = nil
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]any{"errcode": 93000, "errmsg": "invalid webhook url"})
+	})
+	var wxErr *WeixinRobotError
+	if err := wx.Call(context.Background(), "hello"); !errors.As(err, &wxErr) || wxErr.ErrCode != 93000 {
+		t.Fatalf("期望返回企业微信错误，实际: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeWindow 慢日志查询的时间窗口（按自然日，起始日00:00:00至结束日23:59:59）
+type TimeWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// windowLocation 时间窗口统一使用的时区
+func windowLocation() *time.Location {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		return time.FixedZone("CST", 8*3600)
+	}
+	return loc
+}
+
+// DefaultWindow 根据回溯天数计算时间窗口（截止到前一天，至少包含前一天）
+func DefaultWindow(lookBackDays int) TimeWindow {
+	return WindowBefore(time.Now(), lookBackDays)
+}
+
+// WindowBefore 以ref所在日期为基准计算时间窗口，用于补跑错过的调度时保持原窗口
+func WindowBefore(ref time.Time, lookBackDays int) TimeWindow {
+	loc := windowLocation()
+	now := ref.In(loc)
+	d := 0 - lookBackDays
+	if d >= -1 {
+		d = -1
+	}
+	startDay := now.AddDate(0, 0, d)
+	endDay := now.AddDate(0, 0, -1)
+	return TimeWindow{
+		Start: time.Date(startDay.Year(), startDay.Month(), startDay.Day(), 0, 0, 0, 0, loc),
+		End:   time.Date(endDay.Year(), endDay.Month(), endDay.Day(), 23, 59, 59, 0, loc),
+	}
+}
+
+// NewTimeWindow 根据起止日期（格式: 2006-01-02）创建时间窗口
+func NewTimeWindow(startDate, endDate string) (TimeWindow, error) {
+	loc := windowLocation()
+	start, err := time.ParseInLocation(time.DateOnly, startDate, loc)
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("解析起始日期失败: %w", err)
+	}
+	end, err := time.ParseInLocation(time.DateOnly, endDate, loc)
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("解析结束日期失败: %w", err)
+	}
+	if end.Before(start) {
+		return TimeWindow{}, fmt.Errorf("结束日期 %s 早于起始日期 %s", endDate, startDate)
+	}
+	return TimeWindow{
+		Start: start,
+		End:   end.Add(24*time.Hour - time.Second),
+	}, nil
+}
+
+// StartDate 起始日期字符串
+func (w TimeWindow) StartDate() string {
+	return w.Start.Format(time.DateOnly)
+}
+
+// EndDate 结束日期字符串
+func (w TimeWindow) EndDate() string {
+	return w.End.Format(time.DateOnly)
+}
+
+// Key 时间窗口唯一标识，用于记录运行状态
+func (w TimeWindow) Key() string {
+	return w.StartDate() + "_" + w.EndDate()
+}
+
+func (w TimeWindow) String() string {
+	return w.StartDate() + "至" + w.EndDate()
+}
@@ -20,6 +20,9 @@ type AppConfig struct {
 	Global struct {
 		ExportFilePath string `yaml:"EXPORT_FILE_PATH"`
 		LogFilePath    string `yaml:"LOG_FILE"`
+		StateFilePath  string `yaml:"STATE_FILE"` // 运行状态文件，默认位于导出目录下
+		// 运行状态保留天数（按时间窗口结束日期），默认90，为负数时不清理
+		StateRetentionDays int `yaml:"STATE_RETENTION_DAYS"`
 	} `yaml:"GLOBAL"`
 
 	Gitlab struct {
@@ -27,24 +30,209 @@ type AppConfig struct {
 		ProjectID   int    `yaml:"PROJECT_ID"`
 		IssueIID    int    `yaml:"ISSUE_IID"`
 		AccessToken string `yaml:"ACCESS_TOKEN"`
+
+		// 每个时间窗口新建一个Issue（替代固定的ISSUE_IID），标题与描述为text/template，数据模型见 services.ReportData
+		NewIssue struct {
+			Enabled         bool     `yaml:"ENABLED"`
+			Title           string   `yaml:"TITLE"`            // 默认"MySQL慢日志报表 {{.Window}}"
+			Description     string   `yaml:"DESCRIPTION"`      // 为空时使用默认描述
+			DescriptionFile string   `yaml:"DESCRIPTION_FILE"` // 优先于DESCRIPTION
+			Labels          []string `yaml:"LABELS"`
+			AssigneeIDs     []int    `yaml:"ASSIGNEE_IDS"`
+			MilestoneID     int      `yaml:"MILESTONE_ID"`
+			DueDays         int      `yaml:"DUE_DAYS"` // 截止日期为时间窗口结束后的天数，为0则不设置
+			Previous        string   `yaml:"PREVIOUS"` // 上一个时间窗口的Issue：close（关闭）、link（关联）、close_link（关联并关闭），为空不处理
+		} `yaml:"NEW_ISSUE"`
+
+		// 将导出文件提交到仓库（替代Issue附件上传），路径为 PATH/年份/时间窗口/文件名，并维护PATH/README.md索引
+		Repository struct {
+			Enabled       bool   `yaml:"ENABLED"`
+			ProjectID     int    `yaml:"PROJECT_ID"`     // 报表仓库项目ID，默认与GITLAB.PROJECT_ID相同
+			Branch        string `yaml:"BRANCH"`         // 提交分支，默认为项目默认分支（创建合并请求时默认slow-log-reports）
+			Path          string `yaml:"PATH"`           // 报表目录，默认reports
+			CommitMessage string `yaml:"COMMIT_MESSAGE"` // 提交信息模板，默认"Add MySQL slow log report {{.Window.Key}}"
+			MergeRequest  bool   `yaml:"MERGE_REQUEST"`  // 是否创建合并请求（BRANCH合并到TARGET_BRANCH）
+			TargetBranch  string `yaml:"TARGET_BRANCH"`  // 合并请求目标分支，默认为项目默认分支
+		} `yaml:"REPOSITORY"`
+
+		// 每个时间窗口发布一个Wiki页面（PREFIX/时间窗口），并维护列出全部时间窗口的索引页
+		Wiki struct {
+			Enabled      bool   `yaml:"ENABLED"`
+			Prefix       string `yaml:"PREFIX"`        // 页面目录，默认mysql-slow-log
+			IndexTitle   string `yaml:"INDEX_TITLE"`   // 索引页标题，默认PREFIX/index
+			Template     string `yaml:"TEMPLATE"`      // 页面内容模板，数据模型见 services.ReportData
+			TemplateFile string `yaml:"TEMPLATE_FILE"` // 优先于TEMPLATE
+		} `yaml:"WIKI"`
+
+		// 将导出文件存储到通用软件包仓库（替代Issue附件上传），版本号为时间窗口
+		// 下载接口需要令牌，消息中的链接指向项目的软件包页面
+		Packages struct {
+			Enabled        bool   `yaml:"ENABLED"`
+			Name           string `yaml:"NAME"`            // 软件包名称，默认为报表名称
+			RetentionWeeks int    `yaml:"RETENTION_WEEKS"` // 删除时间窗口早于N周前的版本，为0则不清理
+		} `yaml:"PACKAGES"`
 	} `yaml:"GITLAB"`
 
 	WeixinRobot struct {
 		WebhookURL string `yaml:"WEBHOOK_URL"`
 	} `yaml:"WEIXIN_ROBOT"`
 
+	Slack struct {
+		WebhookURL string `yaml:"WEBHOOK_URL"`
+	} `yaml:"SLACK"`
+
+	DingTalk struct {
+		WebhookURL string   `yaml:"WEBHOOK_URL"`
+		Secret     string   `yaml:"SECRET"`   // 加签密钥（SEC开头），为空则不签名
+		Keywords   []string `yaml:"KEYWORDS"` // 安全设置中的自定义关键词，消息不含任一关键词时自动补充
+	} `yaml:"DINGTALK"`
+
+	// 飞书/Lark自定义机器人
+	Feishu struct {
+		WebhookURL string `yaml:"WEBHOOK_URL"`
+		Secret     string `yaml:"SECRET"` // 签名校验密钥，为空则不签名
+	} `yaml:"FEISHU"`
+
+	Email struct {
+		Host            string   `yaml:"HOST"`
+		Port            int      `yaml:"PORT"`
+		Username        string   `yaml:"USERNAME"` // 为空则不认证
+		Password        string   `yaml:"PASSWORD"`
+		From            string   `yaml:"FROM"`
+		TLS             string   `yaml:"TLS"` // starttls（默认）、tls（隐式TLS）、none
+		To              []string `yaml:"TO"`
+		Cc              []string `yaml:"CC"`
+		Subject         string   `yaml:"SUBJECT"`           // 邮件主题模板，默认"MySQL慢查询数据报表 {{.Window}}"
+		MaxAttachmentMB int      `yaml:"MAX_ATTACHMENT_MB"` // 附件总大小上限（按base64编码后计算），超出时压缩为zip，仍超出则不附带附件，默认20
+	} `yaml:"EMAIL"`
+
+	// 通用出站Webhook，POST版本化的JSON文档
+	Webhook struct {
+		URL             string `yaml:"URL"`
+		Secret          string `yaml:"SECRET"`           // HMAC-SHA256签名密钥，签名内容为 X-DataPanel-Timestamp + "." + 请求体，为空则不签名
+		SignatureHeader string `yaml:"SIGNATURE_HEADER"` // 签名请求头，默认X-DataPanel-Signature
+		MaxAttempts     int    `yaml:"MAX_ATTEMPTS"`     // 最大尝试次数，默认3
+	} `yaml:"WEBHOOK"`
+
+	// 通知渠道，为空时沿用WEIXIN_ROBOT配置发送企业微信通知
+	Notifiers []NotifierConfig `yaml:"NOTIFIERS"`
+
 	Query struct {
 		Interval           string `yaml:"INTERVAL"`
 		QueryTimeThreshold string `yaml:"QUERY_TIME_THRESHOLD"`
 		LookBackDays       int    `yaml:"TIME_RANGE_DAYS_AGO"`
 	} `yaml:"QUERY"`
 
-	Ali struct {
-		RDS          string `yaml:"RDS"`
-		AccessKey    string `yaml:"ACCESS_KEY"`
-		AccessSecret string `yaml:"ACCESS_SECRET"`
-		Endpoion     string `yaml:"ENDPOINT"`
-	} `yaml:"ALI"`
+	Ali AliConfig `yaml:"ALI"`
+
+	Daemon struct {
+		Timezone   string `yaml:"TIMEZONE"`    // 调度时区，默认Asia/Shanghai
+		CatchUp    bool   `yaml:"CATCH_UP"`    // 启动时补跑错过的调度
+		ListenAddr string `yaml:"LISTEN_ADDR"` // 控制API监听地址，如 ":8080"，为空则不启动
+		APIToken   string `yaml:"API_TOKEN"`   // 控制API的Bearer Token，为空时不校验且只允许监听本机地址
+
+		MaxWindowDays  int `yaml:"MAX_WINDOW_DAYS"`  // 控制API触发运行的时间窗口最大天数，默认31
+		MaxPendingRuns int `yaml:"MAX_PENDING_RUNS"` // 控制API中未结束（排队或执行中）的运行数上限，默认5
+	} `yaml:"DAEMON"`
+
+	Metrics struct {
+		TextfilePath string `yaml:"TEXTFILE_PATH"` // 单次执行结束后写入的textfile collector文件，如 /var/lib/node_exporter/dataPanelExport.prom
+	} `yaml:"METRICS"`
+
+	// 消息模板，数据模型见 services.ReportData
+	Templates struct {
+		TopN        int    `yaml:"TOP_N"`        // 消息中展示的SQL指纹数量，默认10
+		Comment     string `yaml:"COMMENT"`      // GitLab评论模板
+		CommentFile string `yaml:"COMMENT_FILE"` // GitLab评论模板文件，优先于COMMENT
+	} `yaml:"TEMPLATES"`
+
+	Reports []ReportConfig `yaml:"REPORTS"`
+
+	// 发布目标，同一次运行的导出文件会发布到全部目标；为空时按GITLAB配置发布（Issue附件、仓库或软件包仓库之一）
+	Publishers []PublisherConfig `yaml:"PUBLISHERS"`
+}
+
+// AliConfig 阿里云配置，凭据同时用于RDS与OSS
+type AliConfig struct {
+	RDS      string `yaml:"RDS"`
+	Region   string `yaml:"REGION"`   // 地域，如cn-hangzhou，用于解析RDS访问域名
+	Endpoint string `yaml:"ENDPOINT"` // 覆盖按地域解析的RDS访问域名，一般无需配置
+
+	TimeoutSeconds  int `yaml:"TIMEOUT_SECONDS"`  // 单次API调用的读超时，默认30
+	MaxAttempts     int `yaml:"MAX_ATTEMPTS"`     // 被限流（Throttling.User等）时的最大尝试次数，默认5
+	PageConcurrency int `yaml:"PAGE_CONCURRENCY"` // 慢日志明细同时获取的分页数，默认4
+
+	// 默认导出中包含慢日志统计（aliyun_stats）；为false时仅在运行请求中显式选择该数据源时导出
+	SlowLogStats bool `yaml:"SLOW_LOG_STATS"`
+
+	// 凭据类型：access_key、sts、ram_role_arn、ecs_ram_role、profile；
+	// 为空时配置了ACCESS_KEY则使用access_key，否则使用默认凭据链（环境变量、凭据文件、实例角色等）
+	CredentialType  string `yaml:"CREDENTIAL_TYPE"`
+	AccessKey       string `yaml:"ACCESS_KEY"`        // access_key、sts、ram_role_arn
+	AccessSecret    string `yaml:"ACCESS_SECRET"`     // access_key、sts、ram_role_arn
+	SecurityToken   string `yaml:"SECURITY_TOKEN"`    // sts：STS临时令牌
+	RoleArn         string `yaml:"ROLE_ARN"`          // ram_role_arn：扮演的RAM角色
+	RoleSessionName string `yaml:"ROLE_SESSION_NAME"` // ram_role_arn：会话名称，默认dataPanelExport
+	ExternalID      string `yaml:"EXTERNAL_ID"`       // ram_role_arn：角色外部ID，可选
+	RoleName        string `yaml:"ROLE_NAME"`         // ecs_ram_role：实例RAM角色名称，为空时自动获取
+	Profile         string `yaml:"PROFILE"`           // profile：aliyun CLI配置中的profile名称，为空时使用当前profile
+	CredentialsFile string `yaml:"CREDENTIALS_FILE"`  // profile：aliyun CLI配置文件，默认~/.aliyun/config.json
+}
+
+// PublisherConfig 发布目标配置，GitLab相关目标的连接参数取GITLAB配置段
+type PublisherConfig struct {
+	Type        string `yaml:"TYPE"`          // 目标类型：gitlab_upload、gitlab_repository、gitlab_package、local、sftp、s3、oss
+	Name        string `yaml:"NAME"`          // 目标名称，默认与TYPE相同，用于区分同类型的多个目标
+	FailOnError bool   `yaml:"FAIL_ON_ERROR"` // 发布失败时是否使整个任务失败，默认仅记录日志
+	BaseURL     string `yaml:"BASE_URL"`      // local、sftp、s3、oss：文件对外访问的地址前缀，拼接相对路径后作为通知中的链接
+
+	Dir string `yaml:"DIR"` // local：目标目录；sftp：远程目录
+
+	Host         string `yaml:"HOST"`          // sftp：主机
+	Port         int    `yaml:"PORT"`          // sftp：端口，默认22
+	User         string `yaml:"USER"`          // sftp：用户名
+	IdentityFile string `yaml:"IDENTITY_FILE"` // sftp：私钥文件，为空时使用ssh默认配置
+
+	Endpoint        string `yaml:"ENDPOINT"`          // s3：服务地址，如 https://s3.amazonaws.com、http://minio:9000，可带路径前缀；oss：访问域名，为空时按REGION生成
+	Region          string `yaml:"REGION"`            // s3：签名区域，默认us-east-1；oss：地域，如cn-hangzhou，默认取ALI.REGION
+	Bucket          string `yaml:"BUCKET"`            // s3、oss：存储桶
+	Prefix          string `yaml:"PREFIX"`            // s3、oss：对象键前缀
+	AccessKeyID     string `yaml:"ACCESS_KEY_ID"`     // s3：为空时读取环境变量AWS_ACCESS_KEY_ID
+	SecretAccessKey string `yaml:"SECRET_ACCESS_KEY"` // s3：为空时读取环境变量AWS_SECRET_ACCESS_KEY
+	SessionToken    string `yaml:"SESSION_TOKEN"`     // s3：为空时读取环境变量AWS_SESSION_TOKEN
+	StorageClass    string `yaml:"STORAGE_CLASS"`     // s3：存储类型，如STANDARD_IA；oss：如Standard、IA；为空时使用存储桶默认值
+	PathStyle       bool   `yaml:"PATH_STYLE"`        // s3：使用路径风格地址，MinIO等通常需要开启
+	PartSizeMB      int    `yaml:"PART_SIZE_MB"`      // s3：分片大小，超过该大小的文件分片上传，默认16
+	PresignHours    int    `yaml:"PRESIGN_HOURS"`     // s3、oss：签名下载地址有效期（小时），默认168（s3最长7天），配置BASE_URL时不签名；每次运行重新签名，评论中注明有效期
+	Internal        bool   `yaml:"INTERNAL"`          // oss：按REGION生成内网访问域名（同地域ECS上运行时使用），仅用于上传，下载链接仍按公网域名签名
+}
+
+// NotifierConfig 通知渠道配置，渠道连接参数默认取对应渠道配置段
+type NotifierConfig struct {
+	Type         string `yaml:"TYPE"`          // 渠道类型：weixin、slack、dingtalk、feishu、email、webhook
+	Name         string `yaml:"NAME"`          // 渠道名称，默认与TYPE相同，用于区分同类型的多个渠道
+	Template     string `yaml:"TEMPLATE"`      // 消息模板（text/template），为空时使用渠道默认模板
+	TemplateFile string `yaml:"TEMPLATE_FILE"` // 消息模板文件，优先于TEMPLATE
+	FailOnError  bool   `yaml:"FAIL_ON_ERROR"` // 发送失败时是否使整个任务失败，默认仅记录日志
+	WebhookURL   string `yaml:"WEBHOOK_URL"`   // 覆盖渠道配置段中的Webhook地址
+	Secret       string `yaml:"SECRET"`        // 覆盖渠道配置段中的签名密钥
+	MessageType  string `yaml:"MSG_TYPE"`      // 消息类型，weixin支持markdown（默认）、news、template_card，dingtalk支持markdown（默认）、actionCard
+	SendFiles    bool   `yaml:"SEND_FILES"`    // 是否将导出文件直接发送到渠道
+	SendChart    bool   `yaml:"SEND_CHART"`    // 是否发送汇总图表图片
+
+	MentionUsers   []string `yaml:"MENTION_USERS"`   // 需要@的成员ID（weixin、dingtalk为userid，"@all"为所有人）
+	MentionMobiles []string `yaml:"MENTION_MOBILES"` // 需要@的成员手机号
+
+	To []string `yaml:"TO"` // 覆盖EMAIL配置段中的收件人
+	Cc []string `yaml:"CC"` // 覆盖EMAIL配置段中的抄送人
+}
+
+// ReportConfig 报表定义（守护进程模式按其调度周期执行）
+type ReportConfig struct {
+	Name         string `yaml:"NAME"`
+	Schedule     string `yaml:"SCHEDULE"`       // cron表达式，如 "0 9 * * 1"
+	Jitter       string `yaml:"JITTER"`         // 随机延迟上限，如 "5m"
+	LookBackDays int    `yaml:"LOOK_BACK_DAYS"` // 为0时使用QUERY.TIME_RANGE_DAYS_AGO
 }
 
 // 初始化配置文件（从配置文件读取）
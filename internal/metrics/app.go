@@ -0,0 +1,36 @@
+package metrics
+
+// 导出任务相关指标
+var (
+	// HTTPRequests 出站HTTP请求数（status为状态码，请求未得到响应时为error）
+	HTTPRequests = NewCounterVec("datapanel_http_requests_total",
+		"Outbound HTTP requests by host, method and status.", "host", "method", "status")
+
+	// HTTPRequestDuration 出站HTTP请求耗时
+	HTTPRequestDuration = NewHistogramVec("datapanel_http_request_duration_seconds",
+		"Outbound HTTP request latency by host and method.", nil, "host", "method")
+
+	// StageDuration 导出各阶段耗时
+	StageDuration = NewHistogramVec("datapanel_stage_duration_seconds",
+		"Export run duration per stage.", nil, "report", "stage")
+
+	// RunDuration 导出任务整体耗时
+	RunDuration = NewHistogramVec("datapanel_run_duration_seconds",
+		"Export run total duration.", nil, "report")
+
+	// RowsFetched 最近一次运行各数据源获取到的慢日志条数
+	RowsFetched = NewGaugeVec("datapanel_rows_fetched",
+		"Slow log rows fetched per source in the last run.", "report", "source")
+
+	// RunsTotal 导出任务执行次数（status为success或failure）
+	RunsTotal = NewCounterVec("datapanel_runs_total",
+		"Export runs by report and result.", "report", "status")
+
+	// RunFailures 导出任务失败次数（按失败阶段）
+	RunFailures = NewCounterVec("datapanel_run_failures_total",
+		"Export run failures by report and failed stage.", "report", "stage")
+
+	// LastSuccess 最近一次成功运行的Unix时间戳
+	LastSuccess = NewGaugeVec("datapanel_last_success_timestamp_seconds",
+		"Unix timestamp of the last successful export run.", "report")
+)
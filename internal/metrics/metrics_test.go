@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_WritePrometheusText(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_requests_total", "Requests.", "host", "status")
+	g := r.NewGaugeVec("test_last_success", "Last \"success\".", "report")
+	h := r.NewHistogramVec("test_duration_seconds", "Duration.", []float64{1, 5}, "stage")
+
+	c.Inc("gitlab.example.com", "200")
+	c.Add(2, "gitlab.example.com", "200")
+	g.Set(1760832000, `a"b`)
+	h.Observe(0.5, "upload")
+	h.Observe(3, "upload")
+	h.Observe(10, "upload")
+
+	path := filepath.Join(t.TempDir(), "export.prom")
+	if err := r.WriteTextfile(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		"# TYPE test_requests_total counter\n",
+		`test_requests_total{host="gitlab.example.com",status="200"} 3` + "\n",
+		`test_last_success{report="a\"b"} 1.760832e+09` + "\n",
+		`test_duration_seconds_bucket{stage="upload",le="1"} 1` + "\n",
+		`test_duration_seconds_bucket{stage="upload",le="5"} 2` + "\n",
+		`test_duration_seconds_bucket{stage="upload",le="+Inf"} 3` + "\n",
+		`test_duration_seconds_sum{stage="upload"} 13.5` + "\n",
+		`test_duration_seconds_count{stage="upload"} 3` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("输出缺少 %q:\n%s", want, out)
+		}
+	}
+}
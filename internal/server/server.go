@@ -0,0 +1,403 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"dailyDataPanel/internal/metrics"
+	"dailyDataPanel/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxRunHistory 内存中保留的运行记录数量
+	maxRunHistory = 100
+	// defaultMaxWindowDays 时间窗口默认最大天数（阿里云慢日志按天查询，窗口越长请求越多）
+	defaultMaxWindowDays = 31
+	// defaultMaxPendingRuns 未结束运行数的默认上限
+	defaultMaxPendingRuns = 5
+)
+
+// RunStatus 运行状态
+type RunStatus string
+
+const (
+	StatusPending   RunStatus = "pending"
+	StatusRunning   RunStatus = "running"
+	StatusSucceeded RunStatus = "succeeded"
+	StatusFailed    RunStatus = "failed"
+)
+
+// FileInfo 运行产物文件
+type FileInfo struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	URL  string `json:"url"`
+	path string
+}
+
+// RunInfo 通过控制API触发的运行
+type RunInfo struct {
+	ID         string     `json:"id"`
+	Report     string     `json:"report"`
+	StartDate  string     `json:"start_date"`
+	EndDate    string     `json:"end_date"`
+	Sources    []string   `json:"sources,omitempty"`
+	Force      bool       `json:"force"`
+	Status     RunStatus  `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	Files      []FileInfo `json:"files"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// CreateRunRequest POST /runs 请求体
+type CreateRunRequest struct {
+	Report    string   `json:"report"`
+	StartDate string   `json:"start_date"` // 格式: 2006-01-02，与end_date同时为空时使用报表默认窗口
+	EndDate   string   `json:"end_date"`
+	Sources   []string `json:"sources"`
+	Force     bool     `json:"force"`
+}
+
+// Server 守护进程模式下的HTTP控制API
+type Server struct {
+	ctx    context.Context // 运行使用的上下文，进程退出时取消
+	runner *services.Runner
+	token  string
+
+	maxWindowDays  int
+	maxPendingRuns int
+
+	mu   sync.Mutex
+	runs map[string]*RunInfo
+	wg   sync.WaitGroup
+}
+
+func New(ctx context.Context, runner *services.Runner) *Server {
+	daemon := conf.GetAppConfig().Daemon
+	return &Server{
+		ctx:            ctx,
+		runner:         runner,
+		token:          daemon.APIToken,
+		maxWindowDays:  cmp.Or(daemon.MaxWindowDays, defaultMaxWindowDays),
+		maxPendingRuns: cmp.Or(daemon.MaxPendingRuns, defaultMaxPendingRuns),
+		runs:           make(map[string]*RunInfo),
+	}
+}
+
+// Handler 注册路由
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.Handle("GET /metrics", metrics.Default.Handler())
+	mux.Handle("POST /runs", s.auth(s.handleCreateRun))
+	mux.Handle("GET /runs/{id}", s.auth(s.handleGetRun))
+	mux.Handle("GET /runs/{id}/files/{name}", s.auth(s.handleDownloadFile))
+	return mux
+}
+
+// ListenAndServe 启动HTTP服务，ctx取消后优雅关闭并等待进行中的运行结束
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	// 未配置Token时任何能访问端口的人都可以触发运行、下载文件，仅允许监听本机回环地址
+	if s.token == "" && !isLoopback(addr) {
+		return fmt.Errorf("控制API监听 %s 时必须配置DAEMON.API_TOKEN，或仅监听本机地址（如 127.0.0.1:8080）", addr)
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	conf.GetLogger().Info("控制API已启动", zap.String("addr", addr))
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("控制API启动失败: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	s.wg.Wait()
+	return err
+}
+
+// isLoopback 监听地址是否仅限本机访问（空主机名表示监听全部网卡）
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// auth 校验Bearer Token（未配置API_TOKEN时不校验，此时仅监听本机地址）
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "未授权")
+				return
+			}
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleCreateRun(w http.ResponseWriter, r *http.Request) {
+	var req CreateRunRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "解析请求体失败: "+err.Error())
+			return
+		}
+	}
+	opts, err := buildRunOptions(req, s.maxWindowDays)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := services.NewRunID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	run := &RunInfo{
+		ID:        id,
+		Report:    opts.Report,
+		StartDate: opts.Window.StartDate(),
+		EndDate:   opts.Window.EndDate(),
+		Sources:   opts.Sources,
+		Force:     opts.Force,
+		Status:    StatusPending,
+		Files:     []FileInfo{},
+		CreatedAt: time.Now(),
+	}
+	if !s.addRun(run) {
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("未结束的运行已达上限 %d，请稍后重试", s.maxPendingRuns))
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.execute(run.ID, opts)
+	}()
+
+	w.Header().Set("Location", "/runs/"+run.ID)
+	writeJSON(w, http.StatusAccepted, s.snapshot(run.ID))
+}
+
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	run := s.snapshot(r.PathValue("id"))
+	if run == nil {
+		writeError(w, http.StatusNotFound, "运行记录不存在")
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+func (s *Server) handleDownloadFile(w http.ResponseWriter, r *http.Request) {
+	run := s.snapshot(r.PathValue("id"))
+	if run == nil {
+		writeError(w, http.StatusNotFound, "运行记录不存在")
+		return
+	}
+	name := r.PathValue("name")
+	for _, file := range run.Files {
+		if file.Name != name {
+			continue
+		}
+		f, err := os.Open(file.path)
+		if err != nil {
+			writeError(w, http.StatusGone, "文件已不存在")
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Name))
+		http.ServeContent(w, r, file.Name, info.ModTime(), f)
+		return
+	}
+	writeError(w, http.StatusNotFound, "文件不存在")
+}
+
+// buildRunOptions 根据请求生成运行参数，请求指定的时间窗口不能超过maxWindowDays天
+func buildRunOptions(req CreateRunRequest, maxWindowDays int) (services.RunOptions, error) {
+	report := req.Report
+	if report == "" {
+		report = services.DefaultReportName
+	}
+	opts := services.ReportRunOptions(report)
+	switch {
+	case req.StartDate != "" && req.EndDate != "":
+		window, err := api.NewTimeWindow(req.StartDate, req.EndDate)
+		if err != nil {
+			return opts, err
+		}
+		if days := int(window.End.Sub(window.Start).Hours()/24) + 1; days > maxWindowDays {
+			return opts, fmt.Errorf("时间窗口为 %d 天，超过上限 %d 天", days, maxWindowDays)
+		}
+		opts.Window = window
+	case req.StartDate != "" || req.EndDate != "":
+		return opts, errors.New("start_date与end_date需同时指定")
+	}
+	for _, name := range req.Sources {
+		if !slices.Contains(services.SourceNames(), name) {
+			return opts, fmt.Errorf("未知数据源: %s，可选: %s", name, strings.Join(services.SourceNames(), ", "))
+		}
+	}
+	opts.Sources = req.Sources
+	opts.Force = req.Force
+	return opts, nil
+}
+
+// execute 排队执行导出任务并更新运行状态
+func (s *Server) execute(id string, opts services.RunOptions) {
+	logger := conf.GetLogger().With(zap.String("run_id", id))
+	release, err := s.runner.Acquire(s.ctx)
+	if err != nil {
+		s.finish(id, nil, err)
+		return
+	}
+	defer release()
+
+	s.update(id, func(run *RunInfo) {
+		now := time.Now()
+		run.Status = StatusRunning
+		run.StartedAt = &now
+	})
+	logger.Info("控制API触发的导出任务开始执行")
+	opts.RunID = id
+	result, err := services.Run(s.ctx, opts)
+	if err != nil {
+		logger.Error("控制API触发的导出任务失败: " + err.Error())
+	}
+	s.finish(id, result, err)
+}
+
+// finish 记录运行结果与产物文件
+func (s *Server) finish(id string, result *services.RunResult, err error) {
+	s.update(id, func(run *RunInfo) {
+		now := time.Now()
+		run.FinishedAt = &now
+		run.Status = StatusSucceeded
+		if err != nil {
+			run.Status = StatusFailed
+			run.Error = err.Error()
+		}
+		if result == nil {
+			return
+		}
+		for _, path := range result.Files {
+			info, statErr := os.Stat(path)
+			if statErr != nil {
+				continue
+			}
+			name := filepath.Base(path)
+			run.Files = append(run.Files, FileInfo{
+				Name: name,
+				Size: info.Size(),
+				URL:  "/runs/" + run.ID + "/files/" + name,
+				path: path,
+			})
+		}
+	})
+}
+
+// addRun 保存运行记录，超出上限时淘汰最早结束的记录；未结束的运行数已达上限时不保存并返回false
+func (s *Server) addRun(run *RunInfo) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unfinished := 0
+	for _, r := range s.runs {
+		if r.FinishedAt == nil {
+			unfinished++
+		}
+	}
+	if unfinished >= s.maxPendingRuns {
+		return false
+	}
+	s.runs[run.ID] = run
+	if len(s.runs) <= maxRunHistory {
+		return true
+	}
+	finished := make([]*RunInfo, 0, len(s.runs))
+	for _, r := range s.runs {
+		if r.FinishedAt != nil {
+			finished = append(finished, r)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].CreatedAt.Before(finished[j].CreatedAt) })
+	for _, r := range finished[:max(0, min(len(finished), len(s.runs)-maxRunHistory))] {
+		delete(s.runs, r.ID)
+	}
+	return true
+}
+
+func (s *Server) update(id string, fn func(run *RunInfo)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run, ok := s.runs[id]; ok {
+		fn(run)
+	}
+}
+
+// snapshot 返回运行记录的副本，避免并发读写
+func (s *Server) snapshot(id string) *RunInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return nil
+	}
+	cp := *run
+	cp.Files = append([]FileInfo{}, run.Files...)
+	return &cp
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
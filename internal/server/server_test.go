@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"dailyDataPanel/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_ControlAPI(t *testing.T) {
+	s := New(context.Background(), services.NewRunner())
+	s.token = "secret"
+	h := s.Handler()
+
+	cases := []struct {
+		method, path, body, token string
+		want                      int
+	}{
+		{"GET", "/healthz", "", "", http.StatusOK},
+		{"GET", "/runs/abc", "", "", http.StatusUnauthorized},
+		{"GET", "/runs/abc", "", "secret", http.StatusNotFound},
+		{"GET", "/runs/abc/files/a.csv", "", "secret", http.StatusNotFound},
+		{"POST", "/runs", `{"start_date":"2026-10-12"}`, "secret", http.StatusBadRequest},
+		{"POST", "/runs", `{"start_date":"2026-10-18","end_date":"2026-10-12"}`, "secret", http.StatusBadRequest},
+		{"POST", "/runs", `{"sources":["mongo"]}`, "secret", http.StatusBadRequest},
+		{"POST", "/runs", `{`, "secret", http.StatusBadRequest},
+		{"POST", "/runs", `{"start_date":"2020-01-01","end_date":"2026-10-12"}`, "secret", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("%s %s %s: got %d, want %d (%s)", c.method, c.path, c.body, rec.Code, c.want, rec.Body.String())
+		}
+	}
+}
+
+func Test_ListenWithoutToken(t *testing.T) {
+	for addr, want := range map[string]bool{":8080": false, "0.0.0.0:8080": false, "10.0.0.1:8080": false, "127.0.0.1:8080": true, "[::1]:8080": true, "localhost:8080": true} {
+		if got := isLoopback(addr); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+	s := New(context.Background(), services.NewRunner())
+	s.token = ""
+	if err := s.ListenAndServe(context.Background(), ":0"); err == nil {
+		t.Fatal("未配置Token时不应监听全部网卡")
+	}
+}
+
+// 未结束的运行数达到上限时拒绝新的运行
+func Test_CreateRunPendingLimit(t *testing.T) {
+	s := New(context.Background(), services.NewRunner())
+	s.maxPendingRuns = 1
+	if !s.addRun(&RunInfo{ID: "a", Status: StatusRunning}) {
+		t.Fatal("未达上限时应接受运行")
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("POST", "/runs", strings.NewReader(`{"start_date":"2026-10-12","end_date":"2026-10-18"}`)))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("达到上限时应返回429: %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -126,3 +126,22 @@ func (ali *AliResult) Convert() (string, error) {
 	}
 	return absFilePath, nil
 }
+
+// 提取SQL语句与查询耗时，用于统计SQL指纹
+func (ali *AliResult) SlowQueries() []SlowQuery {
+	queries := make([]SlowQuery, 0, len(ali.Data))
+	for _, record := range ali.Data {
+		var q SlowQuery
+		if record.SQLText != nil {
+			q.SQL = *record.SQLText
+		}
+		switch {
+		case record.QueryTimeMS != nil:
+			q.QueryTime = float64(*record.QueryTimeMS) / 1000
+		case record.QueryTimes != nil:
+			q.QueryTime = float64(*record.QueryTimes)
+		}
+		queries = append(queries, q)
+	}
+	return queries
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"dailyDataPanel/internal/api"
+	"encoding/csv"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+// AliStatsResult 阿里云按SQL聚合的慢日志统计（DescribeSlowLogs），由服务商计算执行次数与耗时汇总
+type AliStatsResult struct {
+	Data     []*api.AliSlowLogStat
+	Fields   []Field
+	BasePath string
+	FileName string
+	FullPath string
+}
+
+// 慢日志统计字段的中文列名
+func (ali *AliStatsResult) FieldsMap() {
+	ali.Fields = []Field{
+		{"ReportTime", "统计日期"},
+		{"DBName", "数据库"},
+		{"SQLHASH", "SQL唯一标识"},
+		{"MySQLTotalExecutionCounts", "执行次数"},
+		{"MySQLTotalExecutionTimes", "总执行时间（秒）"},
+		{"MaxExecutionTimeMS", "最大执行时间（毫秒）"},
+		{"TotalLockTimes", "总锁等待时间（秒）"},
+		{"MaxLockTimeMS", "最大锁等待时间（毫秒）"},
+		{"ParseTotalRowCounts", "解析总行数"},
+		{"ParseMaxRowCount", "最大解析行数"},
+		{"ReturnTotalRowCounts", "返回总行数"},
+		{"ReturnMaxRowCount", "最大返回行数"},
+		{"SQLText", "SQL语句"},
+	}
+}
+
+// 构造返回慢查询统计的中文列名
+func (ali *AliStatsResult) generateColNames() []string {
+	colNames := make([]string, len(ali.Fields))
+	for i, field := range ali.Fields {
+		colNames[i] = field.ColName
+	}
+	return colNames
+}
+
+// 提取行数据成切片(手动构造一行，顺序一一对应)
+func (ali *AliStatsResult) generateRowsData(stat *api.AliSlowLogStat) []string {
+	num := func(v *int64) string { return strconv.FormatInt(tea.Int64Value(v), 10) }
+	return []string{
+		tea.StringValue(stat.ReportTime),
+		tea.StringValue(stat.DBName),
+		tea.StringValue(stat.SQLHASH),
+		num(stat.MySQLTotalExecutionCounts),
+		num(stat.MySQLTotalExecutionTimes),
+		num(stat.MaxExecutionTimeMS),
+		num(stat.TotalLockTimes),
+		num(stat.MaxLockTimeMS),
+		num(stat.ParseTotalRowCounts),
+		num(stat.ParseMaxRowCount),
+		num(stat.ReturnTotalRowCounts),
+		num(stat.ReturnMaxRowCount),
+		tea.StringValue(stat.SQLText),
+	}
+}
+
+// 转换成CSV文件并存储在本地
+func (ali *AliStatsResult) Convert() (string, error) {
+	err := pathIsExist(ali.BasePath)
+	if err != nil {
+		return "", err
+	}
+
+	if beforePath, ok := strings.CutSuffix(ali.BasePath, "/"); ok {
+		ali.BasePath = beforePath
+	}
+	now := time.Now().Format("20060102150405")
+	if ali.FileName == "" {
+		ali.FileName = "unknown_service_mysql_slow_log_stats"
+	}
+	ali.FileName = ali.FileName + "_" + now + ".csv" // 完整文件名
+	absFilePath := ali.BasePath + "/" + ali.FileName // 绝对路径
+	ali.FullPath = absFilePath
+	f, err := os.Create(absFilePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// 避免Window Excel打开中文乱码
+	f.WriteString("\xEF\xBB\xBF")
+
+	w := csv.NewWriter(f)
+	defer w.Flush()
+	if ali.Data == nil {
+		// 空数据直接返回
+		return "", errors.New("无数据")
+	}
+
+	// 写入表头
+	if err := w.Write(ali.generateColNames()); err != nil {
+		return "", errors.New("写入表头发生错误: " + err.Error())
+	}
+	// 写入结果集数据
+	for _, stat := range ali.Data {
+		if err := w.Write(ali.generateRowsData(stat)); err != nil {
+			return "", errors.New("写入数据发生错误: " + err.Error())
+		}
+	}
+	return absFilePath, nil
+}
+
+// 提取SQL语句与聚合后的执行次数、耗时，用于统计SQL指纹
+func (ali *AliStatsResult) SlowQueries() []SlowQuery {
+	queries := make([]SlowQuery, 0, len(ali.Data))
+	for _, stat := range ali.Data {
+		queries = append(queries, SlowQuery{
+			SQL:          tea.StringValue(stat.SQLText),
+			QueryTime:    float64(tea.Int64Value(stat.MySQLTotalExecutionTimes)),
+			Count:        int(tea.Int64Value(stat.MySQLTotalExecutionCounts)),
+			MaxQueryTime: float64(tea.Int64Value(stat.MaxExecutionTimeMS)) / 1000,
+		})
+	}
+	return queries
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+// 汇总图表布局
+const (
+	chartWidth     = 800
+	chartPadding   = 20
+	chartRowHeight = 36
+	chartBarHeight = 24
+	chartLabelW    = 60  // 左侧序号区域宽度
+	chartValueW    = 140 // 右侧数值区域宽度
+	chartGlyphSize = 3   // 字形放大倍数
+)
+
+var (
+	chartBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	chartText       = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	chartPalette    = []color.RGBA{
+		{0x54, 0x70, 0xc6, 0xff},
+		{0x91, 0xcc, 0x75, 0xff},
+		{0xfa, 0xc8, 0x58, 0xff},
+		{0xee, 0x66, 0x66, 0xff},
+		{0x73, 0xc0, 0xde, 0xff},
+	}
+)
+
+// chartGlyphs 3x5点阵字形，仅包含图表数值所需字符
+var chartGlyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'.': {"...", "...", "...", "...", ".#."},
+	's': {"...", "###", "##.", "..#", "###"},
+	'#': {"#.#", "###", "#.#", "###", "#.#"},
+}
+
+// chartBar 图表中的一根柱
+type chartBar struct {
+	label string
+	value float64
+	text  string
+	color color.RGBA
+}
+
+// summaryChartBars 图表数据：有SQL指纹时按总耗时展示（序号与.TopFingerprints顺序一致），
+// 否则按数据源展示慢日志条数（序号与.Sources顺序一致）
+func summaryChartBars(data *ReportData) []chartBar {
+	sourceColor := make(map[string]color.RGBA)
+	for i, src := range data.Sources {
+		sourceColor[src.Label] = chartPalette[i%len(chartPalette)]
+	}
+
+	var bars []chartBar
+	if len(data.TopFingerprints) > 0 {
+		for i, fp := range data.TopFingerprints {
+			bars = append(bars, chartBar{
+				label: fmt.Sprintf("#%d", i+1),
+				value: fp.TotalTime,
+				text:  fmt.Sprintf("%.1fs", fp.TotalTime),
+				color: sourceColor[fp.Source],
+			})
+		}
+		return bars
+	}
+	for i, src := range data.Sources {
+		bars = append(bars, chartBar{
+			label: fmt.Sprintf("#%d", i+1),
+			value: float64(src.Rows),
+			text:  fmt.Sprintf("%d", src.Rows),
+			color: chartPalette[i%len(chartPalette)],
+		})
+	}
+	return bars
+}
+
+// renderSummaryChart 将报表数据渲染为PNG横向柱状图，柱颜色区分数据源
+func renderSummaryChart(data *ReportData) ([]byte, error) {
+	bars := summaryChartBars(data)
+	if len(bars) == 0 {
+		return nil, errors.New("没有可绘制的数据")
+	}
+
+	height := chartPadding*2 + chartRowHeight*len(bars)
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{chartBackground}, image.Point{}, draw.Src)
+
+	maxValue := 0.0
+	for _, bar := range bars {
+		maxValue = max(maxValue, bar.value)
+	}
+	barAreaW := chartWidth - chartPadding*2 - chartLabelW - chartValueW
+	glyphOffset := (chartRowHeight - 5*chartGlyphSize) / 2
+
+	for i, bar := range bars {
+		top := chartPadding + i*chartRowHeight
+		drawChartText(img, chartPadding, top+glyphOffset, bar.label)
+
+		w := 1
+		if maxValue > 0 {
+			w = max(1, int(bar.value/maxValue*float64(barAreaW)))
+		}
+		left := chartPadding + chartLabelW
+		barTop := top + (chartRowHeight-chartBarHeight)/2
+		draw.Draw(img, image.Rect(left, barTop, left+w, barTop+chartBarHeight), &image.Uniform{bar.color}, image.Point{}, draw.Src)
+
+		drawChartText(img, left+w+10, top+glyphOffset, bar.text)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("生成图表失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// drawChartText 使用点阵字形绘制文本，不支持的字符留空
+func drawChartText(img *image.RGBA, x, y int, text string) {
+	for _, r := range text {
+		if glyph, ok := chartGlyphs[r]; ok {
+			for row, line := range glyph {
+				for col, c := range line {
+					if c != '#' {
+						continue
+					}
+					px, py := x+col*chartGlyphSize, y+row*chartGlyphSize
+					draw.Draw(img, image.Rect(px, py, px+chartGlyphSize, py+chartGlyphSize), &image.Uniform{chartText}, image.Point{}, draw.Src)
+				}
+			}
+		}
+		x += 4 * chartGlyphSize
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_CronNext(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	base := time.Date(2026, 10, 19, 10, 30, 0, 0, loc) // 周一
+
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 9 * * 1", time.Date(2026, 10, 26, 9, 0, 0, 0, loc)},
+		{"*/15 * * * *", time.Date(2026, 10, 19, 10, 45, 0, 0, loc)},
+		{"30 10 * * MON-FRI", time.Date(2026, 10, 20, 10, 30, 0, 0, loc)},
+		{"0 0 1 * *", time.Date(2026, 11, 1, 0, 0, 0, 0, loc)},
+		{"0 8 15 * 7", time.Date(2026, 10, 25, 8, 0, 0, 0, loc)},
+		{"@daily", time.Date(2026, 10, 20, 0, 0, 0, 0, loc)},
+	}
+	for _, c := range cases {
+		s, err := ParseCron(c.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		if got := s.Next(base); !got.Equal(c.want) {
+			t.Errorf("%s: got %v, want %v", c.expr, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := ParseCron(bad); err == nil {
+			t.Errorf("%s: 期望解析失败", bad)
+		}
+	}
+}
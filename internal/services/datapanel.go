@@ -2,84 +2,391 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
 	"dailyDataPanel/internal/api"
 	"dailyDataPanel/internal/conf"
+	"dailyDataPanel/internal/metrics"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 )
 
-func Run() {
-	logger := conf.GetLogger()
-	logger.Info("初始化完成")
-	defer conf.CloseLogger()
-	logger.Info("开始Grafana MySQL慢查询日志导出与上传...")
+// DefaultReportName 默认报表名称（MySQL慢日志周报）
+const DefaultReportName = "mysql_slow_log_weekly"
 
-	// 创建Grafana客户端并获取数据
-	ctx := context.Background()
-	grafanaClient := api.NewGrafanaClient()
-	logger.Info("获取慢日志数据", zap.String("action", "Request API"))
+// RunOptions 单次导出运行的参数
+type RunOptions struct {
+	Report  string         // 报表名称，与时间窗口共同标识一次运行
+	Window  api.TimeWindow // 慢日志查询时间窗口
+	Sources []string       // 导出的数据源，为空时导出全部
+	Force   bool           // 忽略已完成步骤，强制全部重新执行
+	RunID   string         // 运行ID，为空时自动生成
+}
 
-	grafanaResp, err := grafanaClient.GetMySQLSlowQueryData(ctx)
-	if err != nil {
-		logger.Fatal("获取Grafana仪表盘数据失败: " + err.Error())
+// NewRunID 生成运行ID
+func NewRunID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成运行ID失败: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// DefaultRunOptions 根据配置生成默认运行参数
+func DefaultRunOptions() RunOptions {
+	appConf := conf.GetAppConfig()
+	return RunOptions{
+		Report: DefaultReportName,
+		Window: api.DefaultWindow(appConf.Query.LookBackDays),
+	}
+}
+
+// ReportRunOptions 根据REPORTS中的报表定义生成运行参数，未定义时使用默认回溯天数
+func ReportRunOptions(report string) RunOptions {
+	for _, cfg := range conf.GetAppConfig().Reports {
+		if cfg.Name == report {
+			return reportRunOptions(cfg, time.Now())
+		}
 	}
-	logger.Info(fmt.Sprintf("成功获取到 %d 条慢日志数据", grafanaResp.Responses[0].Hits.Total.Value), zap.String("who", "阿里云自建数据库"))
+	opts := DefaultRunOptions()
+	opts.Report = report
+	return opts
+}
 
-	// TODO：使用阿里云API获取服务商的慢日志
+// stateFilePath 运行状态文件路径
+func stateFilePath() string {
 	appConf := conf.GetAppConfig()
-	aliResp, err := api.DescribeSlowLogRecords(appConf.Ali.RDS)
+	if appConf.Global.StateFilePath != "" {
+		return appConf.Global.StateFilePath
+	}
+	base := appConf.Global.ExportFilePath
+	if base == "" {
+		base = "/tmp"
+	}
+	return filepath.Join(base, ".dataPanelExport_state.json")
+}
+
+// fileExists 判断文件是否存在
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// RunResult 单次导出运行的结果
+type RunResult struct {
+	Files []string // 本地生成的CSV文件路径
+}
+
+// stateReport 运行状态中的报表标识，导出的数据源与默认不同时单独记录，避免影响完整运行
+func (opts RunOptions) stateReport() string {
+	if len(opts.Sources) == 0 || isDefaultSources(opts.Sources) {
+		return opts.Report
+	}
+	sources := slices.Clone(opts.Sources)
+	slices.Sort(sources)
+	return opts.Report + "[" + strings.Join(sources, ",") + "]"
+}
+
+// observeStage 执行阶段并记录耗时，失败时按阶段累加失败次数
+func observeStage(report, stage string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	metrics.StageDuration.Observe(time.Since(start).Seconds(), report, stage)
+	if err != nil {
+		metrics.RunFailures.Inc(report, stage)
+	}
+	return err
+}
+
+// Run 执行一次导出任务并记录运行指标
+func Run(ctx context.Context, opts RunOptions) (*RunResult, error) {
+	if opts.RunID == "" {
+		id, err := NewRunID()
+		if err != nil {
+			return nil, err
+		}
+		opts.RunID = id
+	}
+	start := time.Now()
+	result, err := run(ctx, opts)
+	metrics.RunDuration.Observe(time.Since(start).Seconds(), opts.Report)
 	if err != nil {
-		logger.Fatal("阿里云慢日志获取失败: " + err.Error())
+		metrics.RunsTotal.Inc(opts.Report, "failure")
+		return result, err
 	}
-	logger.Info(fmt.Sprintf("成功获取到 %d 条慢日志数据", len(aliResp)), zap.String("who", "阿里云RDS服务商"))
+	metrics.RunsTotal.Inc(opts.Report, "success")
 
-	// 传入数据将其转换成CSV文件
-	filesPath := make([]string, 0)
-	logger.Info("开始为MySQL慢日志数据制成CSV报表", zap.String("action", "Convert File"))
+	now := time.Now()
+	metrics.LastSuccess.Set(float64(now.Unix()), opts.Report)
+	store, err := stateStore()
+	if err == nil {
+		err = store.MarkSuccess(opts.Report, now)
+	}
+	if err != nil {
+		conf.GetLogger().Warn("记录成功运行时间失败: " + err.Error())
+	}
+	return result, nil
+}
+
+func run(ctx context.Context, opts RunOptions) (*RunResult, error) {
+	logger := conf.GetLogger()
+	logger.Info("开始Grafana MySQL慢查询日志导出与上传...",
+		zap.String("report", opts.Report), zap.String("window", opts.Window.Key()),
+		zap.Strings("sources", opts.Sources), zap.Bool("force", opts.Force))
+
+	sources, err := selectSources(opts.Sources)
+	if err != nil {
+		return nil, err
+	}
+	channels, err := buildChannels()
+	if err != nil {
+		return nil, err
+	}
+	targets, err := buildTargets()
+	if err != nil {
+		return nil, err
+	}
+	store, err := stateStore()
+	if err != nil {
+		return nil, err
+	}
+	if last, ok := store.LastSuccessRun(opts.Report); ok {
+		metrics.LastSuccess.Set(float64(last.Unix()), opts.Report)
+	}
+	tracker, err := store.Tracker(opts.Window.Key(), opts.stateReport(), opts.Force)
+	if err != nil {
+		return nil, err
+	}
+	if done := tracker.DoneSteps(); len(done) > 0 {
+		logger.Info("检测到本时间窗口已完成的步骤，将从失败步骤继续", zap.Strings("steps", done))
+	}
 
-	aliGen := NewConvertor(aliResp, "service_mysql_slow_log_weekly")
-	graGen := NewConvertor(grafanaResp, "main_mysql_slow_log_weekly")
-	// 先服务商后自建
-	for _, gen := range []Convertor{aliGen, graGen} {
-		filePath, err := gen.Convert()
+	// 获取数据并转换成CSV文件（全部目标均已发布时无需再生成文件）
+	logger.Info("开始为MySQL慢日志数据制成CSV报表", zap.String("action", "Convert File"))
+	result := &RunResult{}
+	outputs := make([]convertOutput, len(sources))
+	sourceNames := make([]string, len(sources))
+	for i, src := range sources {
+		sourceNames[i] = src.Name
+	}
+	published := allPublished(tracker, targets, sourceNames)
+	for i, src := range sources {
+		convertStep := "convert:" + src.Name
+		raw, converted := tracker.Done(convertStep)
+		out := parseConvertOutput(raw)
+		if converted && !published && !fileExists(out.Path) {
+			if err := tracker.Invalidate(convertStep); err != nil {
+				return nil, err
+			}
+		}
+		raw, err := tracker.Do(convertStep, func() (string, error) {
+			return convertSource(ctx, opts, src)
+		})
 		if err != nil {
-			logger.Fatal("生成CSV文件失败: " + err.Error())
+			return result, err
+		}
+		outputs[i] = parseConvertOutput(raw)
+		if fileExists(outputs[i].Path) {
+			result.Files = append(result.Files, outputs[i].Path)
 		}
-		filesPath = append(filesPath, filePath)
 	}
 	logger.Info("成功转换为CSV文件", zap.String("action", "Convert"))
 
-	// 上传CSV文件到GitLab
+	// 发布CSV文件到各发布目标
+	data := buildReportData(opts, sources, outputs)
+	artifacts := make([]Artifact, len(sources))
+	for i, src := range sources {
+		artifacts[i] = Artifact{
+			Source: src.Name,
+			Name:   src.FileName + filepath.Ext(outputs[i].Path),
+			Path:   outputs[i].Path,
+		}
+	}
+	err = observeStage(opts.Report, "upload", func() error {
+		return publishTargets(ctx, tracker, targets, artifacts, data)
+	})
+	if err != nil {
+		return result, err
+	}
+	logger.Info("导出文件发布完成")
+
 	gitlab := api.NewGitLabAPI()
-	uploadResults := make([]string, 0)
-	for _, path := range filesPath {
-		uploadRes, err := gitlab.UploadFile(ctx, path)
+	gitlab, err = windowIssue(ctx, gitlab, store, tracker, data)
+	if err != nil {
+		return result, err
+	}
+	resolveGitLabLinks(ctx, gitlab, data)
+	err = observeStage(opts.Report, "comment", func() error {
+		raw, err := tracker.Do("comment", func() (string, error) {
+			tmpl, err := commentTemplate()
+			if err != nil {
+				return "", err
+			}
+			comment, err := executeTemplate(tmpl, data)
+			if err != nil {
+				return "", err
+			}
+			// 以运行状态中的报表标识与时间窗口作为隐藏标记，重跑时更新已有评论而不是重复追加；
+			// 仅导出部分数据源的运行使用单独的标记，不会替换完整报表的评论
+			note, err := gitlab.CommentUpsert(ctx, api.CommentMarker(opts.stateReport()+"/"+opts.Window.Key()), comment)
+			if err != nil {
+				return "", err
+			}
+			out, err := json.Marshal(note)
+			return string(out), err
+		})
+		if err != nil {
+			return fmt.Errorf("GitLab评论失败: %w", err)
+		}
+		var note api.GitLabNote
+		if json.Unmarshal([]byte(raw), &note) == nil {
+			data.NoteURL = note.URL
+		}
+		if data.NoteURL == "" && data.IssueURL != "" && note.ID > 0 {
+			data.NoteURL = api.NotePermalink(data.IssueURL, note.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// 发布Wiki页面，失败不影响通知
+	if conf.GetAppConfig().Gitlab.Wiki.Enabled {
+		wikiURL, err := tracker.Do("wiki", func() (string, error) {
+			return publishWiki(ctx, gitlab, data)
+		})
 		if err != nil {
-			logger.Fatal("上传CSV文件到GitLab发生错误: " + err.Error())
+			logger.Warn("发布GitLab Wiki页面失败: " + err.Error())
+			data.Failures = append(data.Failures, "发布GitLab Wiki页面失败: "+err.Error())
+		}
+		data.WikiURL = wikiURL
+	}
+
+	// 通知各渠道
+	err = observeStage(opts.Report, "notify", func() error {
+		return notifyChannels(ctx, tracker, channels, data)
+	})
+	if err != nil {
+		return result, fmt.Errorf("通知失败: %w", err)
+	}
+	return result, nil
+}
+
+// convertOutput 转换步骤的输出，记录在运行状态中供重跑时生成消息
+type convertOutput struct {
+	Path            string        `json:"path"`
+	Rows            int           `json:"rows"`
+	TopFingerprints []Fingerprint `json:"top_fingerprints,omitempty"`
+}
+
+// parseConvertOutput 解析转换步骤的输出（兼容仅记录文件路径的旧格式）
+func parseConvertOutput(raw string) convertOutput {
+	var out convertOutput
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		out.Path = raw
+	}
+	return out
+}
+
+// convertSource 获取数据源数据并转换成CSV文件，同时统计SQL指纹
+func convertSource(ctx context.Context, opts RunOptions, src dataSource) (string, error) {
+	var (
+		data any
+		out  convertOutput
+	)
+	err := observeStage(opts.Report, "fetch:"+src.Name, func() error {
+		var err error
+		data, out.Rows, err = src.fetch(ctx, opts.Window)
+		if err == nil {
+			metrics.RowsFetched.Set(float64(out.Rows), opts.Report, src.Name)
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = observeStage(opts.Report, "convert:"+src.Name, func() error {
+		gen := NewConvertor(data, src.FileName)
+		if gen == nil {
+			return fmt.Errorf("数据源 %s 不支持转换", src.Name)
 		}
-		uploadResults = append(uploadResults, uploadRes)
+		var err error
+		if out.Path, err = gen.Convert(); err != nil {
+			return fmt.Errorf("生成CSV文件失败: %w", err)
+		}
+		out.TopFingerprints = topFingerprints(src.Label, gen.SlowQueries(), topN())
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
-	logger.Info("GitLab上传文件成功")
+	raw, err := json.Marshal(out)
+	return string(raw), err
+}
 
-	// 计算时间范围
-	startDays := time.Now().AddDate(0, 0, 0-appConf.Query.LookBackDays)
-	endDays := time.Now().AddDate(0, 0, -1)
-	comment := fmt.Sprintf("## %s至%s MySQL慢日志数据导出\n", startDays.Format("2006-01-02"), endDays.Format("2006-01-02"))
-	// > 服务商：%s\n> 主流： %s\n
-	comment += fmt.Sprintf("> 阿里云RDS服务商：%s\n\n> 阿里云自建数据库： %s\n", uploadResults[0], uploadResults[1])
+// buildReportData 汇总各数据源结果生成消息模板数据
+func buildReportData(opts RunOptions, sources []dataSource, outputs []convertOutput) *ReportData {
+	data := &ReportData{
+		RunID:  opts.RunID,
+		Report: opts.Report,
+		Window: opts.Window,
+	}
+	var fingerprints []Fingerprint
+	for i, src := range sources {
+		summary := SourceSummary{
+			Name:     src.Name,
+			Label:    src.Label,
+			Rows:     outputs[i].Rows,
+			FileName: filepath.Base(outputs[i].Path),
+		}
+		// 已发布到全部目标时不会重新生成已清理的导出文件，此时不提供本地路径，附带文件的渠道跳过该文件
+		if fileExists(outputs[i].Path) {
+			summary.FilePath = outputs[i].Path
+		}
+		data.Sources = append(data.Sources, summary)
+		// 统计数据与明细重复，明细同时导出时不重复计数
+		if src.overlaps != "" && slices.ContainsFunc(sources, func(s dataSource) bool { return s.Name == src.overlaps }) {
+			continue
+		}
+		data.TotalRows += outputs[i].Rows
+		fingerprints = append(fingerprints, outputs[i].TopFingerprints...)
+	}
+	sort.SliceStable(fingerprints, func(i, j int) bool {
+		return fingerprints[i].TotalTime > fingerprints[j].TotalTime
+	})
+	if n := topN(); len(fingerprints) > n {
+		fingerprints = fingerprints[:n]
+	}
+	data.TopFingerprints = fingerprints
+	return data
+}
 
-	err = gitlab.CommentCreate(ctx, comment)
+// resolveGitLabLinks 通过GitLab API解析Issue地址并补全上传文件的绝对地址，失败不影响整体流程
+func resolveGitLabLinks(ctx context.Context, gitlab *api.GitLabAPI, data *ReportData) {
+	logger := conf.GetLogger()
+	issueURL, err := gitlab.IssueURL(ctx)
 	if err != nil {
-		logger.Fatal("GitLab评论失败: " + err.Error())
+		logger.Warn("获取GitLab Issue地址失败: " + err.Error())
+		data.Failures = append(data.Failures, "获取GitLab Issue地址失败: "+err.Error())
 	}
+	data.IssueURL = issueURL
 
-	//4. 调用API通知群机器人
-	wx := api.NewWeixinRobotAPI()
-	err = wx.Call(ctx, "<font color=\"warning\">【生产】MySQL慢查询数据报表已更新</font>\n> [跳转详情](http://172.16.1.82/OP/public/issues/9)\n")
+	project, err := gitlab.Project(ctx)
 	if err != nil {
-		logger.Fatal("通知失败: " + err.Error())
+		logger.Warn("获取GitLab项目地址失败: " + err.Error())
+		return
+	}
+	for i := range data.Sources {
+		data.Sources[i].UploadURL = project.AbsoluteURL(data.Sources[i].UploadURL)
 	}
-	logger.Info("企业微信机器人已通知更新")
 }
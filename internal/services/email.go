@@ -0,0 +1,164 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"go.uber.org/zap"
+)
+
+// 默认邮件正文模板（html/template，数据按上下文自动转义）
+const defaultEmailTemplate = `<h3>MySQL慢查询数据报表（{{.Window}}）</h3>
+<table border="1" cellspacing="0" cellpadding="6">
+<tr><th>数据源</th><th>慢日志条数</th><th>文件</th></tr>
+{{range .Sources}}<tr><td>{{.Label}}</td><td>{{.Rows}}</td><td>{{if .UploadURL}}<a href="{{.UploadURL}}">{{.FileName}}</a>{{else}}{{.FileName}}{{end}}</td></tr>
+{{end}}</table>
+{{if .TopFingerprints}}<h4>耗时最高的SQL</h4>
+<table border="1" cellspacing="0" cellpadding="6">
+<tr><th>数据源</th><th>SQL指纹</th><th>次数</th><th>总耗时(s)</th><th>最大耗时(s)</th></tr>
+{{range .TopFingerprints}}<tr><td>{{.Source}}</td><td><code>{{truncate 200 .SQL}}</code></td><td>{{.Count}}</td><td>{{printf "%.2f" .TotalTime}}</td><td>{{printf "%.2f" .MaxQueryTime}}</td></tr>
+{{end}}</table>
+{{end}}{{if .NoteURL}}<p><a href="{{.NoteURL}}">GitLab详情</a></p>
+{{else if .IssueURL}}<p><a href="{{.IssueURL}}">GitLab详情</a></p>
+{{end}}{{if .Failures}}<p style="color:#d46b08">部分步骤失败：{{join .Failures "; "}}</p>
+{{end}}`
+
+const defaultEmailSubject = "MySQL慢查询数据报表 {{.Window}}"
+
+// emailNotifier 邮件通知，正文为渲染后的HTML，附带各数据源导出文件
+type emailNotifier struct {
+	client        *api.SMTPClient
+	to            []string
+	cc            []string
+	subject       *template.Template
+	maxAttachment int64
+}
+
+func (e *emailNotifier) Notify(ctx context.Context, message string, data *ReportData) error {
+	subject, err := executeTemplate(e.subject, data)
+	if err != nil {
+		return err
+	}
+	attachments, note, err := e.attachments(data)
+	if err != nil {
+		return err
+	}
+	if note != "" {
+		message += "<p>" + note + "</p>"
+	}
+	return e.client.Send(ctx, &api.EmailMessage{
+		Subject:     subject,
+		To:          e.to,
+		Cc:          e.cc,
+		HTMLBody:    message,
+		Attachments: attachments,
+	})
+}
+
+// attachments 读取导出文件作为附件；base64编码后的总大小超出上限时压缩为zip，仍超出则不附带并在正文中说明
+func (e *emailNotifier) attachments(data *ReportData) ([]api.EmailAttachment, string, error) {
+	var (
+		attachments []api.EmailAttachment
+		total       int64
+	)
+	for _, src := range data.Sources {
+		if src.FilePath == "" {
+			continue
+		}
+		content, err := os.ReadFile(src.FilePath)
+		if err != nil {
+			return nil, "", fmt.Errorf("读取附件失败: %w", err)
+		}
+		attachments = append(attachments, api.EmailAttachment{FileName: filepath.Base(src.FilePath), Data: content})
+		total += api.EncodedAttachmentSize(len(content))
+	}
+	if total <= e.maxAttachment {
+		return attachments, "", nil
+	}
+
+	archive, err := zipAttachments(attachments)
+	if err != nil {
+		return nil, "", err
+	}
+	if api.EncodedAttachmentSize(len(archive)) <= e.maxAttachment {
+		name := fmt.Sprintf("%s_%s.zip", data.Report, data.Window.Key())
+		return []api.EmailAttachment{{FileName: name, Data: archive}}, "附件超过大小限制，已压缩为zip。", nil
+	}
+
+	conf.GetLogger().Warn("邮件附件压缩后仍超过大小限制，不附带附件",
+		zap.Int64("size", int64(len(archive))), zap.Int64("limit", e.maxAttachment))
+	return nil, "导出文件超过邮件附件大小限制，请通过上方链接下载。", nil
+}
+
+// zipAttachments 将附件压缩为一个zip文件
+func zipAttachments(attachments []api.EmailAttachment) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, att := range attachments {
+		w, err := zw.Create(att.FileName)
+		if err != nil {
+			return nil, fmt.Errorf("压缩附件失败: %w", err)
+		}
+		if _, err := w.Write(att.Data); err != nil {
+			return nil, fmt.Errorf("压缩附件失败: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("压缩附件失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func newEmailNotifier(cfg conf.NotifierConfig) (Notifier, error) {
+	email := conf.GetAppConfig().Email
+	if email.Host == "" || email.From == "" {
+		return nil, errors.New("未配置EMAIL.HOST或EMAIL.FROM")
+	}
+	switch email.TLS {
+	case "", api.SMTPStartTLS, api.SMTPTLS, api.SMTPNone:
+	default:
+		return nil, fmt.Errorf("未知的EMAIL.TLS: %s，可选: starttls, tls, none", email.TLS)
+	}
+	port := email.Port
+	if port == 0 {
+		port = 587
+		if email.TLS == api.SMTPTLS {
+			port = 465
+		}
+	}
+	to := cfg.To
+	if len(to) == 0 {
+		to = email.To
+	}
+	if len(to) == 0 {
+		return nil, errors.New("未配置邮件收件人TO")
+	}
+	cc := cfg.Cc
+	if len(cc) == 0 {
+		cc = email.Cc
+	}
+	subject, err := parseTemplate("email_subject", email.Subject, "", defaultEmailSubject)
+	if err != nil {
+		return nil, err
+	}
+	return &emailNotifier{
+		client:        api.NewSMTPClient(email.Host, port, email.Username, email.Password, email.From, email.TLS),
+		to:            to,
+		cc:            cc,
+		subject:       subject,
+		maxAttachment: int64(cmp.Or(email.MaxAttachmentMB, 20)) << 20,
+	}, nil
+}
+
+func init() {
+	RegisterHTMLNotifier("email", defaultEmailTemplate, newEmailNotifier)
+}
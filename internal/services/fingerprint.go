@@ -0,0 +1,90 @@
+package services
+
+import (
+	"cmp"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SlowQuery 单条慢查询（用于统计SQL指纹），也可以是服务商按SQL聚合后的统计
+type SlowQuery struct {
+	SQL          string
+	QueryTime    float64 // 查询耗时（秒），聚合统计时为总耗时
+	Count        int     // 聚合统计的执行次数，为0时视为单次执行
+	MaxQueryTime float64 // 聚合统计的最大耗时（秒），为0时取QueryTime
+}
+
+// Fingerprint 归一化后的SQL语句统计
+type Fingerprint struct {
+	Source       string  // 数据源展示名称
+	SQL          string  // 归一化后的SQL（字面量替换为?）
+	Sample       string  // 原始SQL示例
+	Count        int     // 出现次数
+	TotalTime    float64 // 总耗时（秒）
+	MaxQueryTime float64 // 最大耗时（秒）
+}
+
+// AvgQueryTime 平均耗时（秒）
+func (f Fingerprint) AvgQueryTime() float64 {
+	if f.Count == 0 {
+		return 0
+	}
+	return f.TotalTime / float64(f.Count)
+}
+
+var (
+	fpComment    = regexp.MustCompile(`(?s)/\*.*?\*/|--[^\n]*`)
+	fpString     = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.)*"`)
+	fpNumber     = regexp.MustCompile(`\b-?\d+(?:\.\d+)?\b|\b0x[0-9a-fA-F]+\b`)
+	fpInList     = regexp.MustCompile(`(?i)\bin\s*\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	fpValues     = regexp.MustCompile(`(?i)\bvalues\s*\([^)]*\)(?:\s*,\s*\([^)]*\))*`)
+	fpWhitespace = regexp.MustCompile(`\s+`)
+)
+
+// fingerprintSQL 归一化SQL：去注释、字面量替换为?、合并IN列表与多行VALUES、压缩空白并转小写
+func fingerprintSQL(sql string) string {
+	s := fpComment.ReplaceAllString(sql, " ")
+	s = fpString.ReplaceAllString(s, "?")
+	s = fpNumber.ReplaceAllString(s, "?")
+	s = fpInList.ReplaceAllString(s, "in(?+)")
+	s = fpValues.ReplaceAllString(s, "values(?+)")
+	s = fpWhitespace.ReplaceAllString(s, " ")
+	return strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), ";")))
+}
+
+// topFingerprints 按总耗时降序返回前n个SQL指纹
+func topFingerprints(source string, queries []SlowQuery, n int) []Fingerprint {
+	stats := make(map[string]*Fingerprint)
+	for _, q := range queries {
+		if strings.TrimSpace(q.SQL) == "" {
+			continue
+		}
+		key := fingerprintSQL(q.SQL)
+		fp, ok := stats[key]
+		if !ok {
+			fp = &Fingerprint{Source: source, SQL: key, Sample: q.SQL}
+			stats[key] = fp
+		}
+		fp.Count += max(q.Count, 1)
+		fp.TotalTime += q.QueryTime
+		if maxTime := cmp.Or(q.MaxQueryTime, q.QueryTime); maxTime > fp.MaxQueryTime {
+			fp.MaxQueryTime = maxTime
+		}
+	}
+
+	result := make([]Fingerprint, 0, len(stats))
+	for _, fp := range stats {
+		result = append(result, *fp)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalTime != result[j].TotalTime {
+			return result[i].TotalTime > result[j].TotalTime
+		}
+		return result[i].Count > result[j].Count
+	})
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+package services
+
+import "testing"
+
+func Test_FingerprintSQL(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM t1 WHERE id = 10 AND name = 'abc';":          "select * from t1 where id = ? and name = ?",
+		"select *  from t1\n where id in (1, 2,3) /* hint */":       "select * from t1 where id in(?+)",
+		"INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y')":            "insert into t (a, b) values(?+)",
+		"UPDATE t SET c = c - 1.5 WHERE k = \"v\" -- trailing note": "update t set c = c - ? where k = ?",
+		"SELECT * FROM logs WHERE id = 0x1F AND msg = 'it''s fine'": "select * from logs where id = ? and msg = ?",
+	}
+	for in, want := range cases {
+		if got := fingerprintSQL(in); got != want {
+			t.Errorf("fingerprintSQL(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	top := topFingerprints("src", []SlowQuery{
+		{SQL: "select * from a where id = 1", QueryTime: 1},
+		{SQL: "select * from a where id = 2", QueryTime: 3},
+		{SQL: "select * from b where id = 1", QueryTime: 2},
+		{SQL: "", QueryTime: 9},
+	}, 1)
+	if len(top) != 1 || top[0].Count != 2 || top[0].TotalTime != 4 || top[0].MaxQueryTime != 3 {
+		t.Fatalf("topFingerprints 结果不符合预期: %+v", top)
+	}
+}
+
+// 服务商聚合后的统计按执行次数与最大耗时合并
+func Test_TopFingerprintsAggregated(t *testing.T) {
+	top := topFingerprints("src", []SlowQuery{
+		{SQL: "select * from a where id = 1", QueryTime: 120, Count: 40, MaxQueryTime: 9},
+		{SQL: "select * from a where id = 2", QueryTime: 5},
+	}, 1)
+	if len(top) != 1 || top[0].Count != 41 || top[0].TotalTime != 125 || top[0].MaxQueryTime != 9 {
+		t.Fatalf("topFingerprints 聚合结果不符合预期: %+v", top)
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 按时间窗口新建Issue的默认模板
+const (
+	defaultIssueTitleTemplate       = "MySQL慢日志报表 {{.Window}}"
+	defaultIssueDescriptionTemplate = `## {{.Window}} MySQL慢日志报表
+{{range .Sources}}
+- {{.Label}}：{{.Rows}}条
+{{- end}}
+`
+)
+
+// windowIssue 按配置为时间窗口新建Issue，返回指向该Issue的GitLab客户端；未启用时原样返回。
+// Issue按（时间窗口, 报表）记录，同一时间窗口的重跑与仅导出部分数据源的运行复用同一个Issue；
+// 仅新建Issue的运行处理更早时间窗口的Issue，关闭或关联失败不影响整体流程
+func windowIssue(ctx context.Context, gitlab *api.GitLabAPI, store *StateStore, tracker *StepTracker, data *ReportData) (*api.GitLabAPI, error) {
+	cfg := conf.GetAppConfig().Gitlab.NewIssue
+	if !cfg.Enabled {
+		return gitlab, nil
+	}
+
+	window := data.Window.Key()
+	previous, hasPrevious := store.LastIssue(data.Report)
+	raw, err := tracker.Do("issue", func() (string, error) {
+		var out issueStepOutput
+		if iid, ok := store.WindowIssue(window, data.Report); ok {
+			out.IID, out.Reused = iid, true
+		} else if hasPrevious && previous.Window == window {
+			out.IID, out.Reused = previous.IID, true
+		} else {
+			opts, err := issueOptions(data)
+			if err != nil {
+				return "", err
+			}
+			issue, err := gitlab.CreateIssue(ctx, opts)
+			if err != nil {
+				return "", err
+			}
+			out.GitLabIssue = *issue
+		}
+		raw, err := json.Marshal(out)
+		return string(raw), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("新建GitLab Issue失败: %w", err)
+	}
+	var issue issueStepOutput
+	if err := json.Unmarshal([]byte(raw), &issue); err != nil || issue.IID == 0 {
+		return nil, fmt.Errorf("解析新建的Issue失败: %s", raw)
+	}
+	if err := store.MarkIssue(data.Report, IssueRecord{Window: window, IID: issue.IID}); err != nil {
+		return nil, err
+	}
+	issueAPI := gitlab.WithIssue(uint(issue.IID))
+
+	// 只处理更早时间窗口的Issue：补跑旧时间窗口时不能关闭或关联较新的Issue
+	if cfg.Previous != "" && !issue.Reused && hasPrevious && previous.Window < window && previous.IID != issue.IID {
+		_, err := tracker.Do("issue:previous", func() (string, error) {
+			return "", handlePreviousIssue(ctx, issueAPI, cfg.Previous, uint(previous.IID))
+		})
+		if err != nil {
+			conf.GetLogger().Warn("处理上一个时间窗口的Issue失败", zap.Int("iid", previous.IID), zap.Error(err))
+			data.Failures = append(data.Failures, fmt.Sprintf("处理上一个Issue #%d 失败: %v", previous.IID, err))
+		}
+	}
+	return issueAPI, nil
+}
+
+// issueStepOutput issue步骤的输出
+type issueStepOutput struct {
+	api.GitLabIssue
+	Reused bool `json:"reused,omitempty"` // 复用了同一时间窗口已有的Issue
+}
+
+// handlePreviousIssue 关联和/或关闭上一个时间窗口的Issue
+func handlePreviousIssue(ctx context.Context, gitlab *api.GitLabAPI, mode string, previousIID uint) error {
+	switch mode {
+	case "link":
+		return gitlab.LinkIssue(ctx, previousIID)
+	case "close":
+		return gitlab.CloseIssue(ctx, previousIID)
+	case "close_link":
+		return errors.Join(gitlab.LinkIssue(ctx, previousIID), gitlab.CloseIssue(ctx, previousIID))
+	default:
+		return fmt.Errorf("未知的GITLAB.NEW_ISSUE.PREVIOUS: %s，可选: close, link, close_link", mode)
+	}
+}
+
+// issueOptions 按模板与配置生成新建Issue的参数
+func issueOptions(data *ReportData) (api.GitLabIssueOptions, error) {
+	cfg := conf.GetAppConfig().Gitlab.NewIssue
+	titleTmpl, err := parseTemplate("issue_title", cfg.Title, "", defaultIssueTitleTemplate)
+	if err != nil {
+		return api.GitLabIssueOptions{}, err
+	}
+	descTmpl, err := parseTemplate("issue_description", cfg.Description, cfg.DescriptionFile, defaultIssueDescriptionTemplate)
+	if err != nil {
+		return api.GitLabIssueOptions{}, err
+	}
+	title, err := executeTemplate(titleTmpl, data)
+	if err != nil {
+		return api.GitLabIssueOptions{}, err
+	}
+	description, err := executeTemplate(descTmpl, data)
+	if err != nil {
+		return api.GitLabIssueOptions{}, err
+	}
+
+	opts := api.GitLabIssueOptions{
+		Title:       strings.TrimSpace(title),
+		Description: description,
+		Labels:      strings.Join(cfg.Labels, ","),
+		AssigneeIDs: cfg.AssigneeIDs,
+		MilestoneID: cfg.MilestoneID,
+	}
+	if cfg.DueDays > 0 {
+		opts.DueDate = data.Window.End.AddDate(0, 0, cfg.DueDays).Format(time.DateOnly)
+	}
+	return opts, nil
+}
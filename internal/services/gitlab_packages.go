@@ -0,0 +1,120 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// packageName 通用软件包名称
+func packageName(report string) string {
+	return cmp.Or(conf.GetAppConfig().Gitlab.Packages.Name, report)
+}
+
+// gitlabPackagePublisher 上传到通用软件包仓库（版本号为时间窗口），上传后清理过期版本
+type gitlabPackagePublisher struct {
+	gitlab *api.GitLabAPI
+}
+
+func (p *gitlabPackagePublisher) Publish(ctx context.Context, artifacts []Artifact, data *ReportData) ([]PublishedFile, error) {
+	name, version := packageName(data.Report), data.Window.Key()
+	fileURLs := make([]string, 0, len(artifacts))
+	for _, a := range artifacts {
+		fileURL, err := p.gitlab.UploadGenericPackage(ctx, name, version, a.Name, a.Path)
+		if err != nil {
+			return nil, err
+		}
+		fileURLs = append(fileURLs, fileURL)
+	}
+
+	// 下载接口需要携带令牌，消息中优先链接到软件包页面，获取失败时退回下载接口地址
+	pageURL, err := packagePageURL(ctx, p.gitlab, name, version)
+	if err != nil {
+		conf.GetLogger().Warn("获取软件包页面地址失败，使用下载接口地址", zap.Error(err))
+	}
+	files := make([]PublishedFile, 0, len(artifacts))
+	for i, a := range artifacts {
+		link := cmp.Or(pageURL, fileURLs[i])
+		files = append(files, PublishedFile{URL: link, Markdown: fmt.Sprintf("[%s](%s)", a.Name, link)})
+	}
+	cleanupPackagesQuietly(ctx, p.gitlab, data.Report, version)
+	return files, nil
+}
+
+// packagePageURL 软件包版本在项目软件包仓库中的页面地址
+func packagePageURL(ctx context.Context, gitlab *api.GitLabAPI, name, version string) (string, error) {
+	packages, err := gitlab.ListGenericPackages(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	for _, pkg := range packages {
+		if pkg.Version != version {
+			continue
+		}
+		project, err := gitlab.Project(ctx)
+		if err != nil {
+			return "", err
+		}
+		return project.PackageURL(pkg.ID), nil
+	}
+	return "", fmt.Errorf("未找到软件包 %s 的版本 %s", name, version)
+}
+
+// cleanupPackages 删除时间窗口早于保留期的软件包版本（不删除本次发布的版本keep，以便补跑旧时间窗口），返回删除的版本
+func cleanupPackages(ctx context.Context, gitlab *api.GitLabAPI, report, keep string, now time.Time) ([]string, error) {
+	weeks := conf.GetAppConfig().Gitlab.Packages.RetentionWeeks
+	if weeks <= 0 {
+		return nil, nil
+	}
+	cutoff := now.AddDate(0, 0, -7*weeks)
+	packages, err := gitlab.ListGenericPackages(ctx, packageName(report))
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for _, pkg := range packages {
+		if pkg.Version == keep || !packageExpired(pkg, cutoff) {
+			continue
+		}
+		if err := gitlab.DeletePackage(ctx, pkg.ID); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, pkg.Version)
+	}
+	return deleted, nil
+}
+
+// packageExpired 版本号为时间窗口时按窗口结束时间判断，否则按创建时间判断
+func packageExpired(pkg api.GitLabPackage, cutoff time.Time) bool {
+	if start, end, ok := strings.Cut(pkg.Version, "_"); ok {
+		if window, err := api.NewTimeWindow(start, end); err == nil {
+			return window.End.Before(cutoff)
+		}
+	}
+	return !pkg.CreatedAt.IsZero() && pkg.CreatedAt.Before(cutoff)
+}
+
+// cleanupPackagesQuietly 清理过期版本，失败只记录日志
+func cleanupPackagesQuietly(ctx context.Context, gitlab *api.GitLabAPI, report, keep string) {
+	logger := conf.GetLogger()
+	deleted, err := cleanupPackages(ctx, gitlab, report, keep, time.Now())
+	if len(deleted) > 0 {
+		logger.Info("已清理过期的软件包版本", zap.Strings("versions", deleted))
+	}
+	if err != nil {
+		logger.Warn("清理过期的软件包版本失败", zap.Error(err))
+	}
+}
+
+func init() {
+	RegisterPublisher("gitlab_package", func(cfg conf.PublisherConfig) (Publisher, error) {
+		return &gitlabPackagePublisher{gitlab: api.NewGitLabAPI()}, nil
+	})
+}
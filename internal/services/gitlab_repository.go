@@ -0,0 +1,182 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const defaultRepositoryCommitMessage = "Add MySQL slow log report {{.Window.Key}}"
+
+// repositoryTarget 报表仓库的提交位置
+type repositoryTarget struct {
+	gitlab       *api.GitLabAPI
+	project      *api.GitLabProject
+	branch       string
+	startBranch  string // 分支不存在时的起点分支
+	targetBranch string // 合并请求目标分支，为空则不创建合并请求
+	root         string // 报表目录
+}
+
+// newRepositoryTarget 按配置确定提交分支与目录，未配置分支时使用项目默认分支
+func newRepositoryTarget(ctx context.Context, gitlab *api.GitLabAPI) (*repositoryTarget, error) {
+	cfg := conf.GetAppConfig().Gitlab.Repository
+	if cfg.ProjectID > 0 {
+		gitlab = gitlab.WithProject(uint(cfg.ProjectID))
+	}
+	project, err := gitlab.Project(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defaultBranch := cmp.Or(project.DefaultBranch, "main")
+	target := &repositoryTarget{
+		gitlab:  gitlab,
+		project: project,
+		branch:  cmp.Or(cfg.Branch, defaultBranch),
+		root:    strings.Trim(cmp.Or(cfg.Path, "reports"), "/"),
+	}
+	if cfg.MergeRequest {
+		target.targetBranch = cmp.Or(cfg.TargetBranch, defaultBranch)
+		target.branch = cmp.Or(cfg.Branch, "slow-log-reports")
+		if target.branch == target.targetBranch {
+			return nil, fmt.Errorf("报表提交分支与合并请求目标分支相同: %s", target.branch)
+		}
+		exists, err := gitlab.BranchExists(ctx, target.branch)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			target.startBranch = target.targetBranch
+		}
+	}
+	return target, nil
+}
+
+// ref 读取已有文件所用的分支
+func (t *repositoryTarget) ref() string {
+	return cmp.Or(t.startBranch, t.branch)
+}
+
+// gitlabRepositoryPublisher 将导出文件与索引README在一次提交中写入报表仓库
+type gitlabRepositoryPublisher struct {
+	gitlab *api.GitLabAPI
+}
+
+func (p *gitlabRepositoryPublisher) Publish(ctx context.Context, artifacts []Artifact, data *ReportData) ([]PublishedFile, error) {
+	target, err := newRepositoryTarget(ctx, p.gitlab)
+	if err != nil {
+		return nil, err
+	}
+	project := target.project
+	existing, err := target.gitlab.ListTree(ctx, target.ref(), target.root)
+	if err != nil {
+		return nil, err
+	}
+	existingFiles := make(map[string]bool)
+	for _, entry := range existing {
+		if entry.Type == "blob" {
+			existingFiles[entry.Path] = true
+		}
+	}
+	action := func(filePath string) string {
+		if existingFiles[filePath] {
+			return "update"
+		}
+		existingFiles[filePath] = true
+		return "create"
+	}
+
+	var (
+		actions []api.GitLabCommitAction
+		files   = make([]PublishedFile, 0, len(artifacts))
+	)
+	for _, a := range artifacts {
+		content, err := os.ReadFile(a.Path)
+		if err != nil {
+			return nil, fmt.Errorf("读取导出文件失败: %w", err)
+		}
+		filePath := path.Join(target.root, artifactPath(data.Window, a.Name))
+		actions = append(actions, api.GitLabCommitAction{
+			Action:   action(filePath),
+			FilePath: filePath,
+			Content:  api.Base64Content(content),
+			Encoding: "base64",
+		})
+		files = append(files, PublishedFile{URL: project.BlobURL(target.branch, filePath)})
+	}
+
+	indexPath := path.Join(target.root, "README.md")
+	actions = append(actions, api.GitLabCommitAction{
+		Action:   action(indexPath),
+		FilePath: indexPath,
+		Content:  repositoryIndex(target.root, existingFiles),
+	})
+
+	tmpl, err := parseTemplate("commit_message", conf.GetAppConfig().Gitlab.Repository.CommitMessage, "", defaultRepositoryCommitMessage)
+	if err != nil {
+		return nil, err
+	}
+	message, err := executeTemplate(tmpl, data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := target.gitlab.CreateCommit(ctx, target.branch, target.startBranch, message, actions); err != nil {
+		return nil, err
+	}
+
+	if target.targetBranch != "" {
+		mr, err := target.gitlab.EnsureMergeRequest(ctx, target.branch, target.targetBranch, message, "由dataPanelExport自动创建")
+		if err != nil {
+			return nil, err
+		}
+		conf.GetLogger().Info("报表合并请求: " + mr.WebURL)
+	}
+	return files, nil
+}
+
+// repositoryIndex 生成报表索引：按时间窗口倒序列出报表目录下的全部文件（相对链接）
+func repositoryIndex(root string, files map[string]bool) string {
+	windows := make(map[string][]string)
+	for filePath := range files {
+		rel, ok := strings.CutPrefix(filePath, root+"/")
+		if !ok {
+			continue
+		}
+		// 年份/时间窗口/文件名
+		parts := strings.Split(rel, "/")
+		if len(parts) != 3 {
+			continue
+		}
+		windows[parts[1]] = append(windows[parts[1]], rel)
+	}
+	keys := make([]string, 0, len(windows))
+	for key := range windows {
+		keys = append(keys, key)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	var b strings.Builder
+	b.WriteString("# MySQL慢日志报表\n\n此文件由dataPanelExport自动生成，请勿手动修改。\n\n| 时间窗口 | 文件 |\n| --- | --- |\n")
+	for _, key := range keys {
+		rels := windows[key]
+		sort.Strings(rels)
+		links := make([]string, 0, len(rels))
+		for _, rel := range rels {
+			links = append(links, fmt.Sprintf("[%s](%s)", path.Base(rel), rel))
+		}
+		fmt.Fprintf(&b, "| %s | %s |\n", strings.Replace(key, "_", "至", 1), strings.Join(links, "<br>"))
+	}
+	return b.String()
+}
+
+func init() {
+	RegisterPublisher("gitlab_repository", func(cfg conf.PublisherConfig) (Publisher, error) {
+		return &gitlabRepositoryPublisher{gitlab: api.NewGitLabAPI()}, nil
+	})
+}
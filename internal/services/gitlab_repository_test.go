@@ -0,0 +1,27 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_RepositoryIndex(t *testing.T) {
+	index := repositoryIndex("reports", map[string]bool{
+		"reports/README.md": true,
+		"reports/2026/2026-10-05_2026-10-11/main_mysql_slow_log_weekly.csv":    true,
+		"reports/2026/2026-10-12_2026-10-18/main_mysql_slow_log_weekly.csv":    true,
+		"reports/2026/2026-10-12_2026-10-18/service_mysql_slow_log_weekly.csv": true,
+		"other/2026/2026-10-12_2026-10-18/ignored.csv":                         true,
+	})
+	lines := strings.Split(strings.TrimSpace(index), "\n")
+	want := []string{
+		"| 2026-10-12至2026-10-18 | [main_mysql_slow_log_weekly.csv](2026/2026-10-12_2026-10-18/main_mysql_slow_log_weekly.csv)<br>[service_mysql_slow_log_weekly.csv](2026/2026-10-12_2026-10-18/service_mysql_slow_log_weekly.csv) |",
+		"| 2026-10-05至2026-10-11 | [main_mysql_slow_log_weekly.csv](2026/2026-10-05_2026-10-11/main_mysql_slow_log_weekly.csv) |",
+	}
+	got := lines[len(lines)-2:]
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("索引第%d行不符合预期:\n got: %s\nwant: %s", i, got[i], want[i])
+		}
+	}
+}
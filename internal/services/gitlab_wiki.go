@@ -0,0 +1,79 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// 默认Wiki页面模板。页面长期保存，优先链接不会过期的地址；只有签名地址时注明有效期
+const defaultWikiTemplate = `# MySQL慢日志报表 {{.Window}}
+
+| 数据源 | 慢日志条数 | 文件 |
+| --- | --- | --- |
+{{range .Sources}}| {{.Label}} | {{.Rows}} | {{if .PermanentURL}}[{{.FileName}}]({{.PermanentURL}}){{else if .UploadURL}}{{.FileName}}（签名下载链接有效期至 {{.UploadExpires.Format "2006-01-02 15:04"}}）{{else}}{{.FileName}}{{end}} |
+{{end}}
+{{if .TopFingerprints}}## 耗时最高的SQL
+
+| 数据源 | SQL指纹 | 次数 | 总耗时(s) | 平均耗时(s) | 最大耗时(s) |
+| --- | --- | --- | --- | --- | --- |
+{{range .TopFingerprints}}| {{.Source}} | ` + "`{{truncate 200 .SQL}}`" + ` | {{.Count}} | {{printf "%.2f" .TotalTime}} | {{printf "%.2f" .AvgQueryTime}} | {{printf "%.2f" .MaxQueryTime}} |
+{{end}}{{end}}
+{{if .NoteURL}}[GitLab评论]({{.NoteURL}}){{else if .IssueURL}}[GitLab Issue]({{.IssueURL}}){{end}}
+`
+
+// publishWiki 发布本时间窗口的Wiki页面并更新索引页，返回页面地址
+func publishWiki(ctx context.Context, gitlab *api.GitLabAPI, data *ReportData) (string, error) {
+	cfg := conf.GetAppConfig().Gitlab.Wiki
+	prefix := strings.Trim(cmp.Or(cfg.Prefix, "mysql-slow-log"), "/")
+	indexTitle := cmp.Or(cfg.IndexTitle, prefix+"/index")
+
+	tmpl, err := parseTemplate("wiki", cfg.Template, cfg.TemplateFile, defaultWikiTemplate)
+	if err != nil {
+		return "", err
+	}
+	content, err := executeTemplate(tmpl, data)
+	if err != nil {
+		return "", err
+	}
+	page, err := gitlab.UpsertWikiPage(ctx, prefix+"/"+data.Window.Key(), content)
+	if err != nil {
+		return "", err
+	}
+
+	project, err := gitlab.Project(ctx)
+	if err != nil {
+		return "", err
+	}
+	pages, err := gitlab.ListWikiPages(ctx)
+	if err != nil {
+		return "", err
+	}
+	if _, err := gitlab.UpsertWikiPage(ctx, indexTitle, wikiIndex(project, prefix, api.WikiSlug(indexTitle), pages)); err != nil {
+		return "", err
+	}
+	return project.WikiURL(page.Slug), nil
+}
+
+// wikiIndex 生成索引页：按时间窗口倒序列出目录下的全部页面
+func wikiIndex(project *api.GitLabProject, prefix, indexSlug string, pages []api.GitLabWikiPage) string {
+	var slugs []string
+	for _, page := range pages {
+		if strings.HasPrefix(page.Slug, prefix+"/") && page.Slug != indexSlug {
+			slugs = append(slugs, page.Slug)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(slugs)))
+
+	var b strings.Builder
+	b.WriteString("# MySQL慢日志报表\n\n此页面由dataPanelExport自动生成，请勿手动修改。\n\n")
+	for _, slug := range slugs {
+		fmt.Fprintf(&b, "- [%s](%s)\n", strings.Replace(path.Base(slug), "_", "至", 1), project.WikiURL(slug))
+	}
+	return b.String()
+}
@@ -0,0 +1,515 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Notifier 通知渠道
+type Notifier interface {
+	// Notify 发送通知，message为按渠道模板渲染后的文本，data为渲染所用的报表数据
+	Notify(ctx context.Context, message string, data *ReportData) error
+}
+
+// NotifyStep 执行通知中的一部分（如拆分后的一条消息），已送达的部分在重跑时跳过
+type NotifyStep func(part string, send func() error) error
+
+// StepNotifier 一次通知由多条消息组成的渠道，逐条记录发送进度，避免失败重跑时重复发送已送达的消息
+type StepNotifier interface {
+	Notifier
+	NotifySteps(ctx context.Context, message string, data *ReportData, step NotifyStep) error
+}
+
+// NotifierFactory 根据渠道配置创建通知渠道
+type NotifierFactory func(cfg conf.NotifierConfig) (Notifier, error)
+
+// notifierType 已注册的通知渠道类型
+type notifierType struct {
+	factory         NotifierFactory
+	defaultTemplate string
+	html            bool // 消息为HTML，模板使用html/template自动转义
+}
+
+var (
+	notifierMu    sync.RWMutex
+	notifierTypes = make(map[string]notifierType)
+)
+
+// RegisterNotifier 注册通知渠道类型及其默认消息模板
+func RegisterNotifier(typ, defaultTemplate string, factory NotifierFactory) {
+	notifierMu.Lock()
+	defer notifierMu.Unlock()
+	notifierTypes[typ] = notifierType{factory: factory, defaultTemplate: defaultTemplate}
+}
+
+// RegisterHTMLNotifier 注册消息为HTML的通知渠道类型，其模板按html/template解析
+func RegisterHTMLNotifier(typ, defaultTemplate string, factory NotifierFactory) {
+	notifierMu.Lock()
+	defer notifierMu.Unlock()
+	notifierTypes[typ] = notifierType{factory: factory, defaultTemplate: defaultTemplate, html: true}
+}
+
+// NotifierTypes 已注册的通知渠道类型
+func NotifierTypes() []string {
+	notifierMu.RLock()
+	defer notifierMu.RUnlock()
+	return registeredNotifierTypes()
+}
+
+// registeredNotifierTypes 调用方需持有锁
+func registeredNotifierTypes() []string {
+	types := make([]string, 0, len(notifierTypes))
+	for typ := range notifierTypes {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// channel 已按配置创建的通知渠道
+type channel struct {
+	name        string
+	notifier    Notifier
+	tmpl        messageTemplate
+	failOnError bool
+}
+
+// render 按渠道模板渲染消息
+func (c *channel) render(data *ReportData) (string, error) {
+	return executeTemplate(c.tmpl, data)
+}
+
+// channelName 渠道名称，默认与类型相同
+func channelName(cfg conf.NotifierConfig) string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return cfg.Type
+}
+
+// parseChannelTemplate 解析渠道模板（调用方需持有锁）
+func parseChannelTemplate(cfg conf.NotifierConfig) (messageTemplate, error) {
+	typ, ok := notifierTypes[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("未知通知渠道类型: %s，可选: %s", cfg.Type, strings.Join(registeredNotifierTypes(), ", "))
+	}
+	if typ.html {
+		return parseHTMLTemplate(channelName(cfg), cfg.Template, cfg.TemplateFile, typ.defaultTemplate)
+	}
+	return parseTemplate(channelName(cfg), cfg.Template, cfg.TemplateFile, typ.defaultTemplate)
+}
+
+// channelTemplate 按渠道名称获取其消息模板
+func channelTemplate(name string) (messageTemplate, error) {
+	notifierMu.RLock()
+	defer notifierMu.RUnlock()
+	for _, cfg := range notifierConfigs() {
+		if channelName(cfg) == name {
+			return parseChannelTemplate(cfg)
+		}
+	}
+	return nil, fmt.Errorf("未配置通知渠道: %s", name)
+}
+
+// notifierConfigs 通知渠道配置，未配置NOTIFIERS时兼容旧的WEIXIN_ROBOT配置
+func notifierConfigs() []conf.NotifierConfig {
+	appConf := conf.GetAppConfig()
+	if len(appConf.Notifiers) > 0 {
+		return appConf.Notifiers
+	}
+	if appConf.WeixinRobot.WebhookURL == "" {
+		return nil
+	}
+	return []conf.NotifierConfig{{Type: "weixin", FailOnError: true}}
+}
+
+// buildChannels 按配置创建全部通知渠道
+func buildChannels() ([]*channel, error) {
+	notifierMu.RLock()
+	defer notifierMu.RUnlock()
+
+	var channels []*channel
+	names := make(map[string]bool)
+	for _, cfg := range notifierConfigs() {
+		name := channelName(cfg)
+		if names[name] {
+			return nil, fmt.Errorf("通知渠道名称重复: %s", name)
+		}
+		names[name] = true
+
+		tmpl, err := parseChannelTemplate(cfg)
+		if err != nil {
+			return nil, err
+		}
+		notifier, err := notifierTypes[cfg.Type].factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("创建通知渠道 %s 失败: %w", name, err)
+		}
+		channels = append(channels, &channel{
+			name:        name,
+			notifier:    notifier,
+			tmpl:        tmpl,
+			failOnError: cfg.FailOnError,
+		})
+	}
+	return channels, nil
+}
+
+// notifyChannels 逐个渠道发送通知，已发送成功的渠道在重跑时跳过；
+// 仅FAIL_ON_ERROR的渠道失败会返回错误，其余渠道失败只记录日志
+func notifyChannels(ctx context.Context, tracker *StepTracker, channels []*channel, data *ReportData) error {
+	logger := conf.GetLogger()
+	var errs []error
+	for _, ch := range channels {
+		channelStep := "notify:" + ch.name
+		_, err := tracker.Do(channelStep, func() (string, error) {
+			if legacyNotified(tracker, ch) {
+				return "", nil
+			}
+			message, err := ch.render(data)
+			if err != nil {
+				return "", err
+			}
+			if n, ok := ch.notifier.(StepNotifier); ok {
+				return "", n.NotifySteps(ctx, message, data, func(part string, send func() error) error {
+					_, err := tracker.Do(channelStep+":"+part, func() (string, error) { return "", send() })
+					return err
+				})
+			}
+			return "", ch.notifier.Notify(ctx, message, data)
+		})
+		switch {
+		case err == nil:
+			logger.Info("通知已发送", zap.String("channel", ch.name))
+		case ch.failOnError:
+			errs = append(errs, fmt.Errorf("通知渠道 %s 发送失败: %w", ch.name, err))
+		default:
+			logger.Warn("通知渠道发送失败，已忽略", zap.String("channel", ch.name), zap.Error(err))
+			data.Failures = append(data.Failures, fmt.Sprintf("通知渠道 %s 发送失败: %v", ch.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// legacyNotified 引入通知渠道之前的运行记录：企业微信通知记录为notify步骤，
+// 对应未配置NOTIFIERS时的默认weixin渠道。升级后重跑已通知的时间窗口时不重复发送
+func legacyNotified(tracker *StepTracker, ch *channel) bool {
+	if ch.name != "weixin" {
+		return false
+	}
+	_, ok := tracker.Done("notify")
+	return ok
+}
+
+// webhookURL 渠道配置中的Webhook地址优先，否则使用渠道配置段中的地址
+func webhookURL(cfg conf.NotifierConfig, fallback string) (string, error) {
+	url := cfg.WebhookURL
+	if url == "" {
+		url = fallback
+	}
+	if url == "" {
+		return "", errors.New("未配置WEBHOOK_URL")
+	}
+	return url, nil
+}
+
+// weixinNotifier 企业微信群机器人
+type weixinNotifier struct {
+	robot       *api.WeixinRobotAPI
+	messageType string
+	sendFiles   bool
+	sendChart   bool
+	mentions    api.WeixinMentions
+}
+
+func (w *weixinNotifier) Notify(ctx context.Context, message string, data *ReportData) error {
+	return w.NotifySteps(ctx, message, data, func(part string, send func() error) error { return send() })
+}
+
+// NotifySteps 依次发送主消息（拆分后的每一条）、文件、图表与提醒，每一条作为单独的步骤
+func (w *weixinNotifier) NotifySteps(ctx context.Context, message string, data *ReportData, step NotifyStep) error {
+	if err := w.sendMessage(ctx, message, data, step); err != nil {
+		return err
+	}
+	if w.sendFiles {
+		for _, src := range data.Sources {
+			if src.FilePath == "" {
+				continue
+			}
+			// 重跑时导出文件可能已被清理（已发布到全部目标时不再重新生成），跳过而不使渠道失败
+			if !fileExists(src.FilePath) {
+				conf.GetLogger().Warn("导出文件已不存在，跳过发送", zap.String("source", src.Name), zap.String("path", src.FilePath))
+				continue
+			}
+			if err := step("file:"+src.Name, func() error { return w.robot.SendFile(ctx, src.FilePath) }); err != nil {
+				return err
+			}
+		}
+	}
+	if w.sendChart {
+		err := step("chart", func() error {
+			chart, err := renderSummaryChart(data)
+			if err != nil {
+				return err
+			}
+			return w.robot.SendImage(ctx, chart)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendMessage 按MSG_TYPE发送主消息
+func (w *weixinNotifier) sendMessage(ctx context.Context, message string, data *ReportData, step NotifyStep) error {
+	link := data.NoteURL
+	if link == "" {
+		link = data.IssueURL
+	}
+	title := fmt.Sprintf("MySQL慢查询数据报表 %s", data.Window)
+
+	if w.messageType == "" || w.messageType == "markdown" {
+		parts := w.mentions.MarkdownParts(message)
+		for i, part := range parts {
+			err := step(fmt.Sprintf("part%d", i+1), func() error { return w.robot.SendMarkdown(ctx, part) })
+			if err != nil {
+				return fmt.Errorf("第%d/%d条: %w", i+1, len(parts), err)
+			}
+		}
+		return w.sendMention(ctx, true, step)
+	}
+	if err := step("card", func() error { return w.sendCard(ctx, title, link, message, data) }); err != nil {
+		return err
+	}
+	return w.sendMention(ctx, false, step)
+}
+
+// sendMention 主消息之后另发一条提醒消息，inlined表示按userid的提醒已追加在markdown消息中
+func (w *weixinNotifier) sendMention(ctx context.Context, inlined bool, step NotifyStep) error {
+	if len(w.mentions.UserIDs) == 0 && len(w.mentions.Mobiles) == 0 {
+		return nil
+	}
+	return step("mention", func() error {
+		return w.robot.SendMentionText(ctx, api.WeixinMentionContent, w.mentions, inlined)
+	})
+}
+
+// sendCard 发送图文或模板卡片消息
+func (w *weixinNotifier) sendCard(ctx context.Context, title, link, message string, data *ReportData) error {
+	switch w.messageType {
+	case "news":
+		if link == "" {
+			return errors.New("图文消息需要跳转地址，但未获取到GitLab评论或Issue地址")
+		}
+		return w.robot.SendNews(ctx, []api.WeixinNewsArticle{{
+			Title:       title,
+			Description: truncateBytes(api.WeixinMaxNewsDescBytes, plainText(message)),
+			URL:         link,
+		}})
+	case "template_card":
+		if link == "" {
+			return errors.New("模板卡片消息需要跳转地址，但未获取到GitLab评论或Issue地址")
+		}
+		card := &api.WeixinTemplateCard{
+			MainTitle:    api.WeixinCardTitle{Title: "MySQL慢查询数据报表已更新", Desc: data.Window.String()},
+			SubTitleText: truncate(api.WeixinMaxSubTitleRunes-len("..."), plainText(message)),
+			JumpList:     []api.WeixinCardJump{{Type: 1, Title: "跳转详情", URL: link}},
+			CardAction:   api.WeixinCardAction{Type: 1, URL: link},
+		}
+		for _, src := range data.Sources {
+			card.HorizontalContentList = append(card.HorizontalContentList, api.WeixinCardHorizontal{
+				KeyName: src.Label,
+				Value:   fmt.Sprintf("%d条", src.Rows),
+			})
+		}
+		return w.robot.SendTemplateCard(ctx, card)
+	default:
+		return fmt.Errorf("企业微信不支持的消息类型: %s", w.messageType)
+	}
+}
+
+// dingtalkNotifier 钉钉自定义机器人
+type dingtalkNotifier struct {
+	robot       *api.DingTalkRobotAPI
+	messageType string
+	at          api.DingTalkAt
+}
+
+func (d *dingtalkNotifier) Notify(ctx context.Context, message string, data *ReportData) error {
+	title := fmt.Sprintf("MySQL慢查询数据报表 %s", data.Window)
+	if d.messageType != "actionCard" {
+		return d.robot.SendMarkdown(ctx, title, message, d.at)
+	}
+
+	card := api.DingTalkActionCard{Title: title, Text: message}
+	if link := cmp.Or(data.NoteURL, data.IssueURL); link != "" {
+		card.Buttons = append(card.Buttons, api.DingTalkButton{Title: "跳转详情", ActionURL: link})
+	}
+	for _, src := range data.Sources {
+		if src.UploadURL != "" {
+			card.Buttons = append(card.Buttons, api.DingTalkButton{Title: src.Label, ActionURL: src.UploadURL})
+		}
+	}
+	return d.robot.SendActionCard(ctx, card)
+}
+
+// feishuNotifier 飞书/Lark自定义机器人，以消息卡片展示渲染后的消息、各数据源条数、SQL指纹表格与跳转按钮
+type feishuNotifier struct {
+	robot *api.FeishuRobotAPI
+}
+
+func (f *feishuNotifier) Notify(ctx context.Context, message string, data *ReportData) error {
+	headerColor := "blue"
+	if len(data.Failures) > 0 {
+		headerColor = "orange"
+	}
+	card := api.NewFeishuCard(fmt.Sprintf("MySQL慢查询数据报表 %s", data.Window), headerColor)
+	if strings.TrimSpace(message) != "" {
+		card.AddMarkdown(message)
+	}
+
+	var fields []api.FeishuField
+	for _, src := range data.Sources {
+		fields = append(fields, api.FeishuField{
+			IsShort: true,
+			Text:    api.FeishuText{Tag: "lark_md", Content: fmt.Sprintf("**%s**\n%d条", src.Label, src.Rows)},
+		})
+	}
+	if len(fields) > 0 {
+		card.AddFields(fields)
+	}
+
+	if len(data.TopFingerprints) > 0 {
+		var rows [][]string
+		for i, fp := range data.TopFingerprints {
+			rows = append(rows, []string{
+				strconv.Itoa(i + 1),
+				fp.Source,
+				truncate(120, fp.SQL),
+				strconv.Itoa(fp.Count),
+				fmt.Sprintf("%.2fs", fp.TotalTime),
+			})
+		}
+		card.AddTable([]string{"#", "数据源", "SQL指纹", "次数", "总耗时"}, []int{1, 3, 8, 2, 2}, rows)
+	}
+
+	var buttons []api.FeishuButton
+	if link := cmp.Or(data.NoteURL, data.IssueURL); link != "" {
+		buttons = append(buttons, api.FeishuButton{Title: "GitLab Issue", URL: link, Type: "primary"})
+	}
+	for _, src := range data.Sources {
+		if src.UploadURL != "" {
+			buttons = append(buttons, api.FeishuButton{Title: src.Label, URL: src.UploadURL})
+		}
+	}
+	if len(buttons) > 0 {
+		card.AddButtons(buttons)
+	}
+	return f.robot.SendCard(ctx, card)
+}
+
+// slackNotifier Slack Incoming Webhook
+type slackNotifier struct {
+	slack *api.SlackAPI
+}
+
+func (s *slackNotifier) Notify(ctx context.Context, message string, data *ReportData) error {
+	return s.slack.Call(ctx, message)
+}
+
+func init() {
+	RegisterNotifier("weixin",
+		`<font color="warning">【生产】MySQL慢查询数据报表已更新</font>
+> 时间范围：{{.Window}}
+{{range .Sources}}> {{.Label}}：<font color="comment">{{.Rows}}条</font>
+{{end}}{{if .NoteURL}}> [跳转详情]({{.NoteURL}})
+{{else if .IssueURL}}> [跳转详情]({{.IssueURL}})
+{{end}}`,
+		func(cfg conf.NotifierConfig) (Notifier, error) {
+			url, err := webhookURL(cfg, conf.GetAppConfig().WeixinRobot.WebhookURL)
+			if err != nil {
+				return nil, err
+			}
+			switch cfg.MessageType {
+			case "", "markdown", "news", "template_card":
+			default:
+				return nil, fmt.Errorf("企业微信不支持的消息类型: %s，可选: markdown, news, template_card", cfg.MessageType)
+			}
+			return &weixinNotifier{
+				robot:       api.NewWeixinRobotAPIWithURL(url),
+				messageType: cfg.MessageType,
+				sendFiles:   cfg.SendFiles,
+				sendChart:   cfg.SendChart,
+				mentions:    api.WeixinMentions{UserIDs: cfg.MentionUsers, Mobiles: cfg.MentionMobiles},
+			}, nil
+		})
+
+	RegisterNotifier("dingtalk",
+		`### MySQL慢查询数据报表已更新
+> 时间范围：{{.Window}}
+{{range .Sources}}
+- {{.Label}}：{{.Rows}}条
+{{- end}}
+{{if .NoteURL}}
+[跳转详情]({{.NoteURL}})
+{{else if .IssueURL}}
+[跳转详情]({{.IssueURL}})
+{{end}}`,
+		func(cfg conf.NotifierConfig) (Notifier, error) {
+			dingtalk := conf.GetAppConfig().DingTalk
+			url, err := webhookURL(cfg, dingtalk.WebhookURL)
+			if err != nil {
+				return nil, err
+			}
+			switch cfg.MessageType {
+			case "", "markdown", "actionCard":
+			default:
+				return nil, fmt.Errorf("钉钉不支持的消息类型: %s，可选: markdown, actionCard", cfg.MessageType)
+			}
+			at := api.DingTalkAt{AtMobiles: cfg.MentionMobiles}
+			for _, id := range cfg.MentionUsers {
+				if id == "@all" {
+					at.IsAtAll = true
+					continue
+				}
+				at.AtUserIDs = append(at.AtUserIDs, id)
+			}
+			return &dingtalkNotifier{
+				robot:       api.NewDingTalkRobotAPI(url, cmp.Or(cfg.Secret, dingtalk.Secret), dingtalk.Keywords),
+				messageType: cfg.MessageType,
+				at:          at,
+			}, nil
+		})
+
+	RegisterNotifier("feishu",
+		`{{if .Failures}}**部分步骤失败**：{{join .Failures "; "}}{{end}}`,
+		func(cfg conf.NotifierConfig) (Notifier, error) {
+			feishu := conf.GetAppConfig().Feishu
+			url, err := webhookURL(cfg, feishu.WebhookURL)
+			if err != nil {
+				return nil, err
+			}
+			return &feishuNotifier{robot: api.NewFeishuRobotAPI(url, cmp.Or(cfg.Secret, feishu.Secret))}, nil
+		})
+
+	RegisterNotifier("slack",
+		"*MySQL慢查询数据报表已更新* ({{.Window}})\n{{range .Sources}}• {{.Label}}: {{.Rows}}条\n{{end}}{{if .NoteURL}}<{{.NoteURL}}|跳转详情>\n{{else if .IssueURL}}<{{.IssueURL}}|跳转详情>\n{{end}}",
+		func(cfg conf.NotifierConfig) (Notifier, error) {
+			url, err := webhookURL(cfg, conf.GetAppConfig().Slack.WebhookURL)
+			if err != nil {
+				return nil, err
+			}
+			return &slackNotifier{slack: api.NewSlackAPI(url)}, nil
+		})
+}
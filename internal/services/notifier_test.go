@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+func Test_NotifyChannelsResumeParts(t *testing.T) {
+	conf.Logger = zap.NewNop()
+	var sent []string
+	failSecond := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Markdown struct {
+				Content string `json:"content"`
+			} `json:"markdown"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if strings.HasPrefix(payload.Markdown.Content, "B") && failSecond {
+			failSecond = false
+			json.NewEncoder(w).Encode(map[string]any{"errcode": 45009, "errmsg": "api freq out of limit"})
+			return
+		}
+		sent = append(sent, payload.Markdown.Content[:1])
+		json.NewEncoder(w).Encode(map[string]any{"errcode": 0, "errmsg": "ok"})
+	}))
+	defer srv.Close()
+
+	store, err := OpenStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker, err := store.Tracker("2026-10-12_2026-10-18", DefaultReportName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := parseTemplate("weixin", `{{.Report}}`, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 消息超过4096字节，拆分为A、B两条
+	message := strings.Repeat("A", 3000) + "\n" + strings.Repeat("B", 3000)
+	channels := []*channel{{
+		name:        "weixin",
+		notifier:    &weixinNotifier{robot: api.NewWeixinRobotAPIWithURL(srv.URL)},
+		tmpl:        tmpl,
+		failOnError: true,
+	}}
+	data := &ReportData{Report: message}
+
+	if err := notifyChannels(context.Background(), tracker, channels, data); err == nil {
+		t.Fatal("第二条消息失败时应返回错误")
+	}
+	if err := notifyChannels(context.Background(), tracker, channels, data); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(sent, "") != "AB" {
+		t.Fatalf("重跑时不应重复发送已送达的消息: %v", sent)
+	}
+}
+
+func Test_WeixinCardPlainText(t *testing.T) {
+	message := "<font color=\"warning\">【生产】MySQL慢查询数据报表已更新</font>\n> 时间范围：2026-10-12至2026-10-18\n\n> **主库**：<font color=\"comment\">12条</font>\n> [跳转详情](https://gitlab.example.com/issues/9)\n"
+	want := "【生产】MySQL慢查询数据报表已更新\n时间范围：2026-10-12至2026-10-18\n主库：12条\n跳转详情"
+	if got := plainText(message); got != want {
+		t.Fatalf("plainText 结果不符合预期:\n got: %q\nwant: %q", got, want)
+	}
+	long := truncateBytes(api.WeixinMaxNewsDescBytes, strings.Repeat("慢", 300))
+	if len(long) > api.WeixinMaxNewsDescBytes || !strings.HasSuffix(long, "...") || !utf8.ValidString(long) {
+		t.Fatalf("按字节截断不符合预期: %d 字节", len(long))
+	}
+}
+
+func Test_EmailTemplateEscapes(t *testing.T) {
+	tmpl, err := parseHTMLTemplate("email", "", "", defaultEmailTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := &ReportData{
+		Sources:         []SourceSummary{{Label: "<b>主库</b>", FileName: "a.csv", UploadURL: "javascript:alert(1)"}},
+		TopFingerprints: []Fingerprint{{Source: "主库", SQL: "select * from t where a < ?"}},
+	}
+	out, err := executeTemplate(tmpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"<b>主库</b>", "javascript:", "a < ?"} {
+		if strings.Contains(out, bad) {
+			t.Fatalf("邮件正文未转义 %q:\n%s", bad, out)
+		}
+	}
+	if !strings.Contains(out, "&lt;b&gt;主库&lt;/b&gt;") || !strings.Contains(out, "a &lt; ?") {
+		t.Fatalf("邮件正文转义结果不符合预期:\n%s", out)
+	}
+}
+
+// 导出文件已被清理时跳过发送文件，不使渠道失败
+func Test_WeixinSkipsMissingFile(t *testing.T) {
+	conf.Logger = zap.NewNop()
+	var types []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			MsgType string `json:"msgtype"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		types = append(types, payload.MsgType)
+		json.NewEncoder(w).Encode(map[string]any{"errcode": 0, "errmsg": "ok"})
+	}))
+	defer srv.Close()
+
+	w := &weixinNotifier{robot: api.NewWeixinRobotAPIWithURL(srv.URL), sendFiles: true}
+	data := &ReportData{Sources: []SourceSummary{{Name: "main", FilePath: filepath.Join(t.TempDir(), "missing.csv")}}}
+	if err := w.Notify(context.Background(), "report", data); err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 1 || types[0] != "markdown" {
+		t.Fatalf("只应发送主消息: %v", types)
+	}
+}
+
+// 重跑时导出文件已被清理：不提供本地路径，邮件不附带该文件
+func Test_ReportDataMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "kept.csv")
+	if err := os.WriteFile(kept, []byte("a,b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sources, err := selectSources([]string{"aliyun", "grafana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs := []convertOutput{{Path: filepath.Join(dir, "cleaned.csv"), Rows: 1}, {Path: kept, Rows: 2}}
+	data := buildReportData(RunOptions{Report: DefaultReportName}, sources, outputs)
+	if data.Sources[0].FilePath != "" || data.Sources[0].FileName != "cleaned.csv" || data.Sources[1].FilePath != kept {
+		t.Fatalf("本地路径不符合预期: %+v", data.Sources)
+	}
+
+	e := &emailNotifier{maxAttachment: 1 << 20}
+	attachments, _, err := e.attachments(data)
+	if err != nil || len(attachments) != 1 || attachments[0].FileName != "kept.csv" {
+		t.Fatalf("应只附带仍存在的文件: %v %v", attachments, err)
+	}
+}
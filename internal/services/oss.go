@@ -0,0 +1,87 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"errors"
+	"mime"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ossPublisher 上传到阿里云OSS，使用与RDS相同的阿里云凭据。
+// 对象键为 前缀/报表/年份/时间窗口/文件名，并带有report、window标签，
+// 便于按前缀或标签配置生命周期规则（转低频、归档或过期删除）
+type ossPublisher struct {
+	client       *api.OSSClient
+	prefix       string
+	storageClass string
+	baseURL      string
+	expires      time.Duration
+}
+
+func (p *ossPublisher) Publish(ctx context.Context, artifacts []Artifact, data *ReportData) ([]PublishedFile, error) {
+	files := make([]PublishedFile, 0, len(artifacts))
+	for _, a := range artifacts {
+		rel := path.Join(data.Report, artifactPath(data.Window, a.Name))
+		key := path.Join(p.prefix, rel)
+		opts := api.OSSPutOptions{
+			ContentType:  mime.TypeByExtension(filepath.Ext(a.Name)),
+			StorageClass: p.storageClass,
+			Tags:         map[string]string{"report": data.Report, "window": data.Window.Key()},
+		}
+		if err := p.client.PutObject(ctx, key, a.Path, opts); err != nil {
+			return nil, err
+		}
+
+		if p.baseURL != "" {
+			files = append(files, PublishedFile{URL: joinURL(p.baseURL, rel)})
+			continue
+		}
+		files = append(files, PublishedFile{Key: key})
+	}
+	return files, nil
+}
+
+// SignURL 生成签名下载地址，每次运行重新签名
+func (p *ossPublisher) SignURL(key string) (string, time.Time, error) {
+	fileURL, err := p.client.SignURL(key, p.expires)
+	return fileURL, time.Now().Add(p.expires), err
+}
+
+func init() {
+	RegisterPublisher("oss", func(cfg conf.PublisherConfig) (Publisher, error) {
+		endpoint, publicEndpoint := cfg.Endpoint, ""
+		if endpoint == "" {
+			// 未配置地域时使用阿里云配置段的地域
+			region := cmp.Or(cfg.Region, conf.GetAppConfig().Ali.Region)
+			if region == "" {
+				return nil, errors.New("未配置ENDPOINT或REGION")
+			}
+			// 内网域名只用于上传，下载链接需在外部打开，按公网域名签名
+			endpoint = api.OSSEndpoint(region, cfg.Internal)
+			publicEndpoint = api.OSSEndpoint(region, false)
+		}
+		creds, err := api.AliOSSCredentials()
+		if err != nil {
+			return nil, err
+		}
+		client, err := api.NewOSSClient(api.OSSConfig{Endpoint: endpoint, PublicEndpoint: publicEndpoint, Bucket: cfg.Bucket, Credentials: creds})
+		if err != nil {
+			return nil, err
+		}
+		expires := time.Duration(cmp.Or(cfg.PresignHours, defaultPresignHours)) * time.Hour
+		warnSignedURLs(cfg, expires)
+		return &ossPublisher{
+			client:       client,
+			prefix:       strings.Trim(cfg.Prefix, "/"),
+			storageClass: cfg.StorageClass,
+			baseURL:      cfg.BaseURL,
+			expires:      expires,
+		}, nil
+	})
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Artifact 待发布的导出文件
+type Artifact struct {
+	Source string // 数据源标识
+	Name   string // 发布后的文件名（不含时间戳，便于重跑时覆盖）
+	Path   string // 本地文件路径
+}
+
+// PublishedFile 单个文件的发布结果
+type PublishedFile struct {
+	URL      string    `json:"url,omitempty"`      // 文件的绝对地址
+	Markdown string    `json:"markdown,omitempty"` // Markdown引用文本，为空时使用[Name](URL)
+	Key      string    `json:"key,omitempty"`      // 需要签名下载的对象键，URL在每次运行时重新签名
+	Expires  time.Time `json:"-"`                  // 签名地址的过期时间
+}
+
+// PublishedLink 数据源导出文件在某个发布目标上的链接
+type PublishedLink struct {
+	Publisher string // 发布目标名称
+	URL       string
+}
+
+// Publisher 发布目标
+type Publisher interface {
+	// Publish 发布全部导出文件，返回与artifacts一一对应的发布结果
+	Publish(ctx context.Context, artifacts []Artifact, data *ReportData) ([]PublishedFile, error)
+}
+
+// urlSigner 为对象键生成签名下载地址的发布目标。运行记录中只保存对象键，
+// 重跑时重新签名，避免评论和通知中出现已过期的地址
+type urlSigner interface {
+	SignURL(key string) (string, time.Time, error)
+}
+
+// signFiles 为只记录了对象键的发布结果生成签名下载地址
+func signFiles(p Publisher, files []PublishedFile) error {
+	for i := range files {
+		if files[i].Key == "" {
+			continue
+		}
+		signer, ok := p.(urlSigner)
+		if !ok {
+			return fmt.Errorf("发布目标不支持签名下载地址")
+		}
+		fileURL, expires, err := signer.SignURL(files[i].Key)
+		if err != nil {
+			return err
+		}
+		files[i].URL, files[i].Expires = fileURL, expires
+	}
+	return nil
+}
+
+// PublisherFactory 根据目标配置创建发布目标
+type PublisherFactory func(cfg conf.PublisherConfig) (Publisher, error)
+
+var (
+	publisherMu    sync.RWMutex
+	publisherTypes = make(map[string]PublisherFactory)
+)
+
+// RegisterPublisher 注册发布目标类型
+func RegisterPublisher(typ string, factory PublisherFactory) {
+	publisherMu.Lock()
+	defer publisherMu.Unlock()
+	publisherTypes[typ] = factory
+}
+
+// PublisherTypes 已注册的发布目标类型
+func PublisherTypes() []string {
+	publisherMu.RLock()
+	defer publisherMu.RUnlock()
+	return registeredPublisherTypes()
+}
+
+// registeredPublisherTypes 调用方需持有锁
+func registeredPublisherTypes() []string {
+	types := make([]string, 0, len(publisherTypes))
+	for typ := range publisherTypes {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// target 已按配置创建的发布目标
+type target struct {
+	name        string
+	publisher   Publisher
+	failOnError bool
+}
+
+// publisherName 目标名称，默认与类型相同
+func publisherName(cfg conf.PublisherConfig) string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return cfg.Type
+}
+
+// publisherConfigs 发布目标配置，未配置PUBLISHERS时按GITLAB配置选择一个必须成功的目标
+func publisherConfigs() []conf.PublisherConfig {
+	appConf := conf.GetAppConfig()
+	if len(appConf.Publishers) > 0 {
+		return appConf.Publishers
+	}
+	typ := "gitlab_upload"
+	switch {
+	case appConf.Gitlab.Repository.Enabled:
+		typ = "gitlab_repository"
+	case appConf.Gitlab.Packages.Enabled:
+		typ = "gitlab_package"
+	}
+	return []conf.PublisherConfig{{Type: typ, FailOnError: true}}
+}
+
+// buildTargets 按配置创建全部发布目标
+func buildTargets() ([]*target, error) {
+	publisherMu.RLock()
+	defer publisherMu.RUnlock()
+
+	var targets []*target
+	names := make(map[string]bool)
+	for _, cfg := range publisherConfigs() {
+		name := publisherName(cfg)
+		if names[name] {
+			return nil, fmt.Errorf("发布目标名称重复: %s", name)
+		}
+		names[name] = true
+
+		factory, ok := publisherTypes[cfg.Type]
+		if !ok {
+			return nil, fmt.Errorf("未知发布目标类型: %s，可选: %s", cfg.Type, strings.Join(registeredPublisherTypes(), ", "))
+		}
+		publisher, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("创建发布目标 %s 失败: %w", name, err)
+		}
+		targets = append(targets, &target{name: name, publisher: publisher, failOnError: cfg.FailOnError})
+	}
+	return targets, nil
+}
+
+// publishStep 发布目标的步骤名称
+func publishStep(t *target) string {
+	return "publish:" + t.name
+}
+
+// publishTargets 逐个目标发布导出文件，已发布的目标在重跑时跳过；
+// 各数据源的Upload/UploadURL取第一个发布成功的目标，全部链接记录在Links中。
+// 仅FAIL_ON_ERROR的目标失败会返回错误，其余目标失败只记录日志
+func publishTargets(ctx context.Context, tracker *StepTracker, targets []*target, artifacts []Artifact, data *ReportData) error {
+	logger := conf.GetLogger()
+	var errs []error
+	sources := make([]string, len(artifacts))
+	for i, a := range artifacts {
+		sources[i] = a.Source
+	}
+	for _, t := range targets {
+		raw, err := tracker.Do(publishStep(t), func() (string, error) {
+			files, ok := legacyPublished(tracker, t, sources)
+			if !ok {
+				var err error
+				if files, err = t.publisher.Publish(ctx, artifacts, data); err != nil {
+					return "", err
+				}
+			}
+			if len(files) != len(artifacts) {
+				return "", fmt.Errorf("发布结果数量 %d 与文件数量 %d 不一致", len(files), len(artifacts))
+			}
+			out, err := json.Marshal(files)
+			return string(out), err
+		})
+		var files []PublishedFile
+		if err == nil {
+			if err = json.Unmarshal([]byte(raw), &files); err == nil && len(files) != len(artifacts) {
+				err = fmt.Errorf("发布记录与文件数量不一致，请使用--force重新发布")
+			}
+			if err == nil {
+				err = signFiles(t.publisher, files)
+			}
+		}
+		switch {
+		case err == nil:
+			logger.Info("导出文件已发布", zap.String("publisher", t.name))
+		case t.failOnError:
+			errs = append(errs, fmt.Errorf("发布目标 %s 发布失败: %w", t.name, err))
+			continue
+		default:
+			logger.Warn("发布目标发布失败，已忽略", zap.String("publisher", t.name), zap.Error(err))
+			data.Failures = append(data.Failures, fmt.Sprintf("发布目标 %s 发布失败: %v", t.name, err))
+			continue
+		}
+
+		for i, file := range files {
+			src := &data.Sources[i]
+			src.Links = append(src.Links, PublishedLink{Publisher: t.name, URL: file.URL})
+			if src.Upload == "" {
+				src.Upload = file.Markdown
+				if src.Upload == "" {
+					src.Upload = fmt.Sprintf("[%s](%s)", artifacts[i].Name, file.URL)
+				}
+				src.UploadURL = file.URL
+				src.UploadExpires = file.Expires
+			}
+			if src.PermanentURL == "" && file.Expires.IsZero() {
+				src.PermanentURL = file.URL
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// allPublished 全部目标是否均已发布（此时无需保留本地导出文件）
+func allPublished(tracker *StepTracker, targets []*target, sources []string) bool {
+	for _, t := range targets {
+		if _, ok := tracker.Done(publishStep(t)); ok {
+			continue
+		}
+		if _, ok := legacyPublished(tracker, t, sources); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// legacyPublished 引入发布目标之前的运行记录：提交到报表仓库为publish:repository（各数据源的Markdown链接列表），
+// Issue附件与通用软件包为upload:数据源（Markdown链接）。升级后重跑已发布的时间窗口时沿用其结果，不重复发布
+func legacyPublished(tracker *StepTracker, t *target, sources []string) ([]PublishedFile, bool) {
+	uploads := make([]string, len(sources))
+	switch t.name {
+	case "gitlab_repository":
+		raw, ok := tracker.Done("publish:repository")
+		if !ok || json.Unmarshal([]byte(raw), &uploads) != nil || len(uploads) != len(sources) {
+			return nil, false
+		}
+	case "gitlab_upload", "gitlab_package":
+		for i, src := range sources {
+			raw, ok := tracker.Done("upload:" + src)
+			if !ok {
+				return nil, false
+			}
+			uploads[i] = raw
+		}
+	default:
+		return nil, false
+	}
+	files := make([]PublishedFile, len(uploads))
+	for i, md := range uploads {
+		files[i] = PublishedFile{URL: markdownURL(md), Markdown: md}
+	}
+	return files, true
+}
+
+// artifactPath 文件在目标中的相对路径：年份/时间窗口/文件名
+func artifactPath(window api.TimeWindow, name string) string {
+	return path.Join(window.Start.Format("2006"), window.Key(), name)
+}
+
+// joinURL 拼接对外访问地址
+func joinURL(base, rel string) string {
+	segments := strings.Split(rel, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.Join(segments, "/")
+}
+
+// gitlabUploadPublisher 上传为GitLab项目附件（/projects/:id/uploads）
+type gitlabUploadPublisher struct {
+	gitlab *api.GitLabAPI
+}
+
+func (p *gitlabUploadPublisher) Publish(ctx context.Context, artifacts []Artifact, data *ReportData) ([]PublishedFile, error) {
+	project, err := p.gitlab.Project(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]PublishedFile, 0, len(artifacts))
+	for _, a := range artifacts {
+		markdown, err := p.gitlab.UploadFile(ctx, a.Path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, PublishedFile{URL: project.AbsoluteURL(markdownURL(markdown)), Markdown: markdown})
+	}
+	return files, nil
+}
+
+// localPublisher 复制到本地目录（如挂载的NAS或静态文件服务器目录）
+type localPublisher struct {
+	dir     string
+	baseURL string
+}
+
+func (p *localPublisher) Publish(ctx context.Context, artifacts []Artifact, data *ReportData) ([]PublishedFile, error) {
+	files := make([]PublishedFile, 0, len(artifacts))
+	for _, a := range artifacts {
+		rel := artifactPath(data.Window, a.Name)
+		dst := filepath.Join(p.dir, filepath.FromSlash(rel))
+		if err := copyFile(a.Path, dst); err != nil {
+			return nil, err
+		}
+		fileURL := "file://" + filepath.ToSlash(dst)
+		if p.baseURL != "" {
+			fileURL = joinURL(p.baseURL, rel)
+		}
+		files = append(files, PublishedFile{URL: fileURL})
+	}
+	return files, nil
+}
+
+// copyFile 复制文件，先写入临时文件再重命名
+func copyFile(src, dst string) error {
+	if err := pathIsExist(filepath.Dir(dst)); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("读取导出文件失败: %w", err)
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("创建目标文件失败: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("复制文件失败: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("复制文件失败: %w", err)
+	}
+	return os.Rename(tmp, dst)
+}
+
+// sftpPublisher 通过系统sftp命令（批处理模式，依赖免密登录）上传到远程目录
+type sftpPublisher struct {
+	host         string
+	port         int
+	user         string
+	identityFile string
+	dir          string
+	baseURL      string
+}
+
+func (p *sftpPublisher) Publish(ctx context.Context, artifacts []Artifact, data *ReportData) ([]PublishedFile, error) {
+	var (
+		batch strings.Builder
+		files = make([]PublishedFile, 0, len(artifacts))
+	)
+	// 逐级创建目录，"-"前缀表示忽略已存在等错误
+	windowDir := path.Join(p.dir, path.Dir(artifactPath(data.Window, "x")))
+	for _, dir := range parentDirs(p.dir, windowDir) {
+		fmt.Fprintf(&batch, "-mkdir %s\n", sftpQuote(dir))
+	}
+	for _, a := range artifacts {
+		rel := artifactPath(data.Window, a.Name)
+		remote := path.Join(p.dir, rel)
+		fmt.Fprintf(&batch, "put %s %s\n", sftpQuote(a.Path), sftpQuote(remote))
+
+		fileURL := fmt.Sprintf("sftp://%s@%s:%d%s", p.user, p.host, p.port, remote)
+		if p.baseURL != "" {
+			fileURL = joinURL(p.baseURL, rel)
+		}
+		files = append(files, PublishedFile{URL: fileURL})
+	}
+
+	args := []string{"-b", "-", "-P", strconv.Itoa(p.port), "-o", "BatchMode=yes"}
+	if p.identityFile != "" {
+		args = append(args, "-i", p.identityFile)
+	}
+	args = append(args, p.user+"@"+p.host)
+	cmd := exec.CommandContext(ctx, "sftp", args...)
+	cmd.Stdin = strings.NewReader(batch.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("sftp上传失败: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return files, nil
+}
+
+// parentDirs 返回从base（不含）到dir的各级目录
+func parentDirs(base, dir string) []string {
+	var dirs []string
+	for d := dir; d != base && d != "." && d != "/"; d = path.Dir(d) {
+		dirs = append([]string{d}, dirs...)
+	}
+	return dirs
+}
+
+// sftpQuote 为sftp批处理命令的参数加引号
+func sftpQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func init() {
+	RegisterPublisher("gitlab_upload", func(cfg conf.PublisherConfig) (Publisher, error) {
+		return &gitlabUploadPublisher{gitlab: api.NewGitLabAPI()}, nil
+	})
+	RegisterPublisher("local", func(cfg conf.PublisherConfig) (Publisher, error) {
+		if cfg.Dir == "" {
+			return nil, errors.New("未配置DIR")
+		}
+		return &localPublisher{dir: cfg.Dir, baseURL: cfg.BaseURL}, nil
+	})
+	RegisterPublisher("sftp", func(cfg conf.PublisherConfig) (Publisher, error) {
+		if cfg.Host == "" || cfg.User == "" || cfg.Dir == "" {
+			return nil, errors.New("未配置HOST、USER或DIR")
+		}
+		if _, err := exec.LookPath("sftp"); err != nil {
+			return nil, fmt.Errorf("未找到sftp命令: %w", err)
+		}
+		port := cfg.Port
+		if port == 0 {
+			port = 22
+		}
+		return &sftpPublisher{
+			host:         cfg.Host,
+			port:         port,
+			user:         cfg.User,
+			identityFile: cfg.IdentityFile,
+			dir:          path.Clean(cfg.Dir),
+			baseURL:      cfg.BaseURL,
+		}, nil
+	})
+}
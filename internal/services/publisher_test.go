@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type failingPublisher struct{ calls int }
+
+func (p *failingPublisher) Publish(ctx context.Context, artifacts []Artifact, data *ReportData) ([]PublishedFile, error) {
+	p.calls++
+	return nil, errors.New("boom")
+}
+
+// signingPublisher 只返回对象键，每次签名生成不同的地址
+type signingPublisher struct{ calls, signs int }
+
+func (p *signingPublisher) Publish(ctx context.Context, artifacts []Artifact, data *ReportData) ([]PublishedFile, error) {
+	p.calls++
+	return []PublishedFile{{Key: "reports/main.csv"}}, nil
+}
+
+func (p *signingPublisher) SignURL(key string) (string, time.Time, error) {
+	p.signs++
+	return fmt.Sprintf("https://bucket.example.com/%s?sig=%d", key, p.signs), time.Date(2026, 10, 26, 9, 0, 0, 0, time.Local), nil
+}
+
+func Test_PublishTargets(t *testing.T) {
+	conf.Logger = zap.NewNop()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "main_20261019.csv")
+	if err := os.WriteFile(src, []byte("a,b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	window, err := api.NewTimeWindow("2026-10-12", "2026-10-18")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenStateStore(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker, err := store.Tracker(window.Key(), DefaultReportName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failing := &failingPublisher{}
+	targets := []*target{
+		{name: "backup", publisher: failing},
+		{name: "nas", publisher: &localPublisher{dir: filepath.Join(dir, "nas"), baseURL: "https://files.example.com/reports"}, failOnError: true},
+	}
+	artifacts := []Artifact{{Source: "main", Name: "main_mysql_slow_log.csv", Path: src}}
+	data := &ReportData{Window: window, Sources: []SourceSummary{{Name: "main"}}}
+
+	if err := publishTargets(context.Background(), tracker, targets, artifacts, data); err != nil {
+		t.Fatalf("非必须目标失败不应返回错误: %v", err)
+	}
+	if len(data.Failures) != 1 || failing.calls != 1 {
+		t.Fatalf("应记录非必须目标的失败: %v", data.Failures)
+	}
+	wantURL := "https://files.example.com/reports/2026/2026-10-12_2026-10-18/main_mysql_slow_log.csv"
+	got := data.Sources[0]
+	if got.UploadURL != wantURL || len(got.Links) != 1 || got.Links[0].Publisher != "nas" {
+		t.Fatalf("发布链接不符合预期: %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nas", "2026", "2026-10-12_2026-10-18", "main_mysql_slow_log.csv")); err != nil {
+		t.Fatalf("文件未复制到目标目录: %v", err)
+	}
+	if allPublished(tracker, targets, []string{"main"}) {
+		t.Fatal("存在失败的目标时不应视为全部发布")
+	}
+
+	// 必须成功的目标失败时返回错误
+	targets[0].failOnError = true
+	if err := publishTargets(context.Background(), tracker, targets, artifacts, &ReportData{Window: window, Sources: []SourceSummary{{Name: "main"}}}); err == nil {
+		t.Fatal("FAIL_ON_ERROR目标失败应返回错误")
+	}
+}
+
+// 升级前已提交到报表仓库的时间窗口，重跑时沿用publish:repository的结果而不重复提交
+func Test_PublishTargetsLegacySteps(t *testing.T) {
+	conf.Logger = zap.NewNop()
+	store, err := OpenStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker, err := store.Tracker("2026-10-12_2026-10-18", DefaultReportName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := `["[main.csv](https://gitlab.example.com/ops/reports/-/blob/master/reports/main.csv)"]`
+	if _, err := tracker.Do("publish:repository", func() (string, error) { return legacy, nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := &failingPublisher{}
+	targets := []*target{{name: "gitlab_repository", publisher: publisher, failOnError: true}}
+	if !allPublished(tracker, targets, []string{"main"}) {
+		t.Fatal("旧的提交记录应视为已发布")
+	}
+	data := &ReportData{Sources: []SourceSummary{{Name: "main"}}}
+	artifacts := []Artifact{{Source: "main", Name: "main.csv"}}
+	if err := publishTargets(context.Background(), tracker, targets, artifacts, data); err != nil || publisher.calls != 0 {
+		t.Fatalf("不应重新发布: calls=%d err=%v", publisher.calls, err)
+	}
+	if got := data.Sources[0].UploadURL; got != "https://gitlab.example.com/ops/reports/-/blob/master/reports/main.csv" {
+		t.Fatalf("应沿用旧的发布链接: %s", got)
+	}
+}
+
+// 运行记录中只保存对象键，重跑时不重新上传但重新签名
+func Test_PublishTargetsResign(t *testing.T) {
+	conf.Logger = zap.NewNop()
+	store, err := OpenStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	publisher := &signingPublisher{}
+	targets := []*target{{name: "s3", publisher: publisher, failOnError: true}}
+	artifacts := []Artifact{{Source: "main", Name: "main.csv"}}
+
+	for i := 1; i <= 2; i++ {
+		tracker, err := store.Tracker("2026-10-12_2026-10-18", DefaultReportName, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := &ReportData{Sources: []SourceSummary{{Name: "main"}}}
+		if err := publishTargets(context.Background(), tracker, targets, artifacts, data); err != nil {
+			t.Fatal(err)
+		}
+		want := fmt.Sprintf("https://bucket.example.com/reports/main.csv?sig=%d", i)
+		if got := data.Sources[0]; got.UploadURL != want || got.UploadExpires.IsZero() || got.PermanentURL != "" {
+			t.Fatalf("第%d次运行应重新签名: %+v", i, got)
+		}
+		if raw, _ := tracker.Done(publishStep(targets[0])); raw != `[{"key":"reports/main.csv"}]` {
+			t.Fatalf("运行记录中不应保存签名地址: %s", raw)
+		}
+	}
+	if publisher.calls != 1 {
+		t.Fatalf("重跑不应重新上传: calls=%d", publisher.calls)
+	}
+}
+
+// Wiki页面长期保存，不应写入会过期的签名地址
+func Test_WikiTemplateAvoidsSignedURL(t *testing.T) {
+	tmpl, err := parseTemplate("wiki", "", "", defaultWikiTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Date(2026, 10, 26, 9, 0, 0, 0, time.Local)
+	data := &ReportData{Sources: []SourceSummary{
+		{Label: "主库", FileName: "a.csv", UploadURL: "https://bucket.example.com/a.csv?sig=1", UploadExpires: expires},
+		{Label: "从库", FileName: "b.csv", UploadURL: "https://bucket.example.com/b.csv?sig=1", UploadExpires: expires, PermanentURL: "https://files.example.com/b.csv"},
+	}}
+	out, err := executeTemplate(tmpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "sig=") || !strings.Contains(out, "a.csv（签名下载链接有效期至 2026-10-26 09:00）") || !strings.Contains(out, "[b.csv](https://files.example.com/b.csv)") {
+		t.Fatalf("Wiki页面链接不符合预期:\n%s", out)
+	}
+}
@@ -19,6 +19,7 @@ import (
 type Convertor interface {
 	Convert() (string, error)
 	FieldsMap()
+	SlowQueries() []SlowQuery
 }
 
 // 结果集的结构体
@@ -55,6 +56,12 @@ func NewConvertor(data any, fileName string) Convertor {
 			BasePath: appConf.Global.ExportFilePath,
 			FileName: fileName,
 		}
+	case []*api.AliSlowLogStat:
+		conv = &AliStatsResult{
+			Data:     v,
+			BasePath: appConf.Global.ExportFilePath,
+			FileName: fileName,
+		}
 	default:
 		logger := conf.GetLogger()
 		logger.Warn("不支持，无法转换")
@@ -181,6 +188,35 @@ func (gra *GrafanaResult) generateRowsData(record map[string]any) []string {
 	return row
 }
 
+// 提取SQL语句与查询耗时，用于统计SQL指纹
+func (gra *GrafanaResult) SlowQueries() []SlowQuery {
+	queries := make([]SlowQuery, 0, len(gra.Data))
+	for _, row := range gra.Data {
+		sql, _ := row.Source["sql_statement"].(string)
+		queries = append(queries, SlowQuery{
+			SQL:       sql,
+			QueryTime: toFloat(row.Source["query_time"]),
+		})
+	}
+	return queries
+}
+
+// toFloat 将JSON数值或数字字符串转换为float64
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
+
 // 判断路径是否存在
 func pathIsExist(base string) error {
 	// 创建文件，不存在目录则创建
@@ -0,0 +1,22 @@
+package services
+
+import "context"
+
+// Runner 保证同一进程内导出任务串行执行（single-flight）
+type Runner struct {
+	sem chan struct{}
+}
+
+func NewRunner() *Runner {
+	return &Runner{sem: make(chan struct{}, 1)}
+}
+
+// Acquire 等待前一个任务结束后获取执行权，返回释放函数，等待期间ctx取消则直接返回
+func (r *Runner) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case r.sem <- struct{}{}:
+		return func() { <-r.sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultPresignHours 预签名下载地址的默认有效期（小时）
+const defaultPresignHours = 168
+
+// warnSignedURLs 未配置BASE_URL时通知与评论中使用签名地址，过期后无法下载
+func warnSignedURLs(cfg conf.PublisherConfig, expires time.Duration) {
+	if cfg.BaseURL == "" {
+		conf.GetLogger().Warn("未配置BASE_URL，链接为签名地址，过期后需重新运行以重新签名",
+			zap.String("publisher", publisherName(cfg)), zap.Duration("expires", expires))
+	}
+}
+
+// s3Publisher 上传到S3兼容对象存储（AWS S3、MinIO、OSS的S3兼容接口等）
+type s3Publisher struct {
+	client       *api.S3Client
+	prefix       string
+	storageClass string
+	baseURL      string
+	expires      time.Duration
+}
+
+func (p *s3Publisher) Publish(ctx context.Context, artifacts []Artifact, data *ReportData) ([]PublishedFile, error) {
+	files := make([]PublishedFile, 0, len(artifacts))
+	for _, a := range artifacts {
+		rel := artifactPath(data.Window, a.Name)
+		key := path.Join(p.prefix, rel)
+		opts := api.S3PutOptions{
+			ContentType:  cmp.Or(mime.TypeByExtension(filepath.Ext(a.Name)), "application/octet-stream"),
+			StorageClass: p.storageClass,
+		}
+		if err := p.client.PutObject(ctx, key, a.Path, opts); err != nil {
+			return nil, err
+		}
+
+		if p.baseURL != "" {
+			files = append(files, PublishedFile{URL: joinURL(p.baseURL, rel)})
+			continue
+		}
+		files = append(files, PublishedFile{Key: key})
+	}
+	return files, nil
+}
+
+// SignURL 生成签名下载地址，每次运行重新签名
+func (p *s3Publisher) SignURL(key string) (string, time.Time, error) {
+	fileURL, err := p.client.PresignGetObject(key, p.expires)
+	return fileURL, time.Now().Add(p.expires), err
+}
+
+func init() {
+	RegisterPublisher("s3", func(cfg conf.PublisherConfig) (Publisher, error) {
+		client, err := api.NewS3Client(api.S3Config{
+			Endpoint:        cfg.Endpoint,
+			Region:          cfg.Region,
+			Bucket:          cfg.Bucket,
+			AccessKeyID:     cmp.Or(cfg.AccessKeyID, os.Getenv("AWS_ACCESS_KEY_ID")),
+			SecretAccessKey: cmp.Or(cfg.SecretAccessKey, os.Getenv("AWS_SECRET_ACCESS_KEY")),
+			SessionToken:    cmp.Or(cfg.SessionToken, os.Getenv("AWS_SESSION_TOKEN")),
+			PathStyle:       cfg.PathStyle,
+			PartSize:        int64(cfg.PartSizeMB) << 20,
+		})
+		if err != nil {
+			return nil, err
+		}
+		expires := time.Duration(cmp.Or(cfg.PresignHours, defaultPresignHours)) * time.Hour
+		if expires > api.S3MaxPresignExpiry {
+			return nil, fmt.Errorf("PRESIGN_HOURS不能超过%d", int(api.S3MaxPresignExpiry.Hours()))
+		}
+		warnSignedURLs(cfg, expires)
+		return &s3Publisher{
+			client:       client,
+			prefix:       strings.Trim(cfg.Prefix, "/"),
+			storageClass: cfg.StorageClass,
+			baseURL:      cfg.BaseURL,
+			expires:      expires,
+		}, nil
+	})
+}
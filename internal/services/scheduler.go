@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// scheduledReport 已解析调度参数的报表定义
+type scheduledReport struct {
+	cfg      conf.ReportConfig
+	schedule *CronSchedule
+	jitter   time.Duration
+}
+
+// Scheduler 守护进程模式下按cron表达式调度各报表
+type Scheduler struct {
+	runner  *Runner
+	reports []scheduledReport
+	loc     *time.Location
+	catchUp bool
+	wg      sync.WaitGroup
+}
+
+// NewScheduler 根据REPORTS配置创建调度器
+func NewScheduler(runner *Runner) (*Scheduler, error) {
+	appConf := conf.GetAppConfig()
+	if len(appConf.Reports) == 0 {
+		return nil, errors.New("守护进程模式需要配置REPORTS")
+	}
+
+	tz := appConf.Daemon.Timezone
+	if tz == "" {
+		tz = "Asia/Shanghai"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("加载调度时区失败: %w", err)
+	}
+
+	s := &Scheduler{
+		runner:  runner,
+		loc:     loc,
+		catchUp: appConf.Daemon.CatchUp,
+	}
+	for _, cfg := range appConf.Reports {
+		if cfg.Name == "" {
+			return nil, errors.New("报表定义缺少NAME")
+		}
+		schedule, err := ParseCron(cfg.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("报表 %s 调度表达式无效: %w", cfg.Name, err)
+		}
+		var jitter time.Duration
+		if cfg.Jitter != "" {
+			if jitter, err = time.ParseDuration(cfg.Jitter); err != nil {
+				return nil, fmt.Errorf("报表 %s JITTER无效: %w", cfg.Name, err)
+			}
+		}
+		s.reports = append(s.reports, scheduledReport{cfg: cfg, schedule: schedule, jitter: jitter})
+	}
+	return s, nil
+}
+
+// reportRunOptions 根据报表定义与调度时间生成运行参数
+func reportRunOptions(cfg conf.ReportConfig, scheduledAt time.Time) RunOptions {
+	lookBack := cfg.LookBackDays
+	if lookBack == 0 {
+		lookBack = conf.GetAppConfig().Query.LookBackDays
+	}
+	return RunOptions{
+		Report: cfg.Name,
+		Window: api.WindowBefore(scheduledAt, lookBack),
+	}
+}
+
+// Start 启动调度，阻塞至ctx取消并等待执行中的任务退出
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, rep := range s.reports {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(ctx, rep)
+		}()
+	}
+	<-ctx.Done()
+	s.wg.Wait()
+}
+
+// loop 单个报表的调度循环
+func (s *Scheduler) loop(ctx context.Context, rep scheduledReport) {
+	logger := conf.GetLogger()
+
+	if s.catchUp {
+		if missed, ok := s.missedRun(rep); ok {
+			logger.Info("补跑错过的调度", zap.String("report", rep.cfg.Name), zap.Time("scheduled_at", missed))
+			s.runReport(ctx, rep, missed)
+		}
+	}
+
+	for {
+		next := rep.schedule.Next(time.Now().In(s.loc))
+		if next.IsZero() {
+			logger.Warn("调度表达式没有后续触发时间", zap.String("report", rep.cfg.Name))
+			return
+		}
+		delay := time.Until(next)
+		if rep.jitter > 0 {
+			delay += rand.N(rep.jitter)
+		}
+		logger.Info("下一次调度", zap.String("report", rep.cfg.Name), zap.Time("next", next), zap.Duration("delay", delay))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.runReport(ctx, rep, next)
+	}
+}
+
+// missedRun 判断上次成功执行后是否错过了调度，返回最近一次错过的调度时间
+func (s *Scheduler) missedRun(rep scheduledReport) (time.Time, bool) {
+	store, err := stateStore()
+	if err != nil {
+		conf.GetLogger().Error("读取运行状态失败: " + err.Error())
+		return time.Time{}, false
+	}
+	last, ok := store.LastScheduledRun(rep.cfg.Name)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	now := time.Now()
+	var missed time.Time
+	for t := rep.schedule.Next(last.In(s.loc)); !t.IsZero() && !t.After(now); t = rep.schedule.Next(t) {
+		missed = t
+	}
+	return missed, !missed.IsZero()
+}
+
+// runReport 执行一次调度，已有任务在执行时排队等待，成功后记录调度时间
+func (s *Scheduler) runReport(ctx context.Context, rep scheduledReport, scheduledAt time.Time) {
+	logger := conf.GetLogger().With(zap.String("report", rep.cfg.Name), zap.Time("scheduled_at", scheduledAt))
+
+	release, err := s.runner.Acquire(ctx)
+	if err != nil {
+		logger.Warn("等待上一次导出任务结束时退出: " + err.Error())
+		return
+	}
+	defer release()
+
+	if _, err := Run(ctx, reportRunOptions(rep.cfg, scheduledAt)); err != nil {
+		logger.Error("调度执行失败: " + err.Error())
+		return
+	}
+	store, err := stateStore()
+	if err == nil {
+		err = store.MarkScheduledRun(rep.cfg.Name, scheduledAt)
+	}
+	if err != nil {
+		logger.Error("记录调度时间失败: " + err.Error())
+		return
+	}
+	logger.Info("调度执行完成")
+}
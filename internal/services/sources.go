@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"fmt"
+	"slices"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// dataSource 慢日志数据源定义
+type dataSource struct {
+	Name     string                                                             // 数据源标识，用于选择数据源与记录运行步骤
+	Label    string                                                             // 展示名称
+	FileName string                                                             // 导出文件名前缀
+	fetch    func(ctx context.Context, window api.TimeWindow) (any, int, error) // 返回数据与条数
+
+	optIn    func() bool // 非nil时为可选数据源，返回false时不在默认导出中，需显式选择
+	overlaps string      // 与该数据源重叠的明细数据源，同时导出时不计入总条数与SQL指纹排行
+}
+
+var (
+	aliRDSClientMu     sync.Mutex
+	aliRDSClientCached *api.AliRDSClient
+)
+
+// aliRDSClient 阿里云RDS客户端，首次创建成功后各次运行复用；创建失败不缓存，下次运行重试
+func aliRDSClient() (*api.AliRDSClient, error) {
+	aliRDSClientMu.Lock()
+	defer aliRDSClientMu.Unlock()
+	if aliRDSClientCached != nil {
+		return aliRDSClientCached, nil
+	}
+	client, err := api.NewAliRDSClient(conf.GetAppConfig().Ali)
+	if err != nil {
+		return nil, err
+	}
+	aliRDSClientCached = client
+	return client, nil
+}
+
+// dataSources 已支持的数据源（按导出顺序：先服务商后自建）
+var dataSources = []dataSource{
+	{
+		Name:     "aliyun",
+		Label:    "阿里云RDS服务商",
+		FileName: "service_mysql_slow_log_weekly",
+		fetch: func(ctx context.Context, window api.TimeWindow) (any, int, error) {
+			client, err := aliRDSClient()
+			if err != nil {
+				return nil, 0, err
+			}
+			aliResp, err := client.DescribeSlowLogRecords(ctx, conf.GetAppConfig().Ali.RDS, window)
+			if err != nil {
+				return nil, 0, fmt.Errorf("阿里云慢日志获取失败: %w", err)
+			}
+			conf.GetLogger().Info(fmt.Sprintf("成功获取到 %d 条慢日志数据", len(aliResp)), zap.String("who", "阿里云RDS服务商"))
+			return aliResp, len(aliResp), nil
+		},
+	},
+	{
+		Name:     "aliyun_stats",
+		Label:    "阿里云RDS慢日志统计",
+		FileName: "service_mysql_slow_log_stats_weekly",
+		optIn:    func() bool { return conf.GetAppConfig().Ali.SlowLogStats },
+		overlaps: "aliyun",
+		fetch: func(ctx context.Context, window api.TimeWindow) (any, int, error) {
+			client, err := aliRDSClient()
+			if err != nil {
+				return nil, 0, err
+			}
+			stats, err := client.DescribeSlowLogs(ctx, conf.GetAppConfig().Ali.RDS, window)
+			if err != nil {
+				return nil, 0, fmt.Errorf("阿里云慢日志统计获取失败: %w", err)
+			}
+			conf.GetLogger().Info(fmt.Sprintf("成功获取到 %d 条慢日志统计", len(stats)), zap.String("who", "阿里云RDS服务商"))
+			return stats, len(stats), nil
+		},
+	},
+	{
+		Name:     "grafana",
+		Label:    "阿里云自建数据库",
+		FileName: "main_mysql_slow_log_weekly",
+		fetch: func(ctx context.Context, window api.TimeWindow) (any, int, error) {
+			logger := conf.GetLogger()
+			logger.Info("获取慢日志数据", zap.String("action", "Request API"))
+			grafanaResp, err := api.NewGrafanaClient().GetMySQLSlowQueryData(ctx, window)
+			if err != nil {
+				return nil, 0, fmt.Errorf("获取Grafana仪表盘数据失败: %w", err)
+			}
+			total := grafanaResp.Responses[0].Hits.Total.Value
+			logger.Info(fmt.Sprintf("成功获取到 %d 条慢日志数据", total), zap.String("who", "阿里云自建数据库"))
+			return grafanaResp, total, nil
+		},
+	},
+}
+
+// SourceNames 所有已支持的数据源标识
+func SourceNames() []string {
+	names := make([]string, len(dataSources))
+	for i, src := range dataSources {
+		names[i] = src.Name
+	}
+	return names
+}
+
+// defaultSources 未指定数据源时导出的数据源（不含未开启的可选数据源）
+func defaultSources() []dataSource {
+	sources := make([]dataSource, 0, len(dataSources))
+	for _, src := range dataSources {
+		if src.optIn == nil || src.optIn() {
+			sources = append(sources, src)
+		}
+	}
+	return sources
+}
+
+// isDefaultSources 指定的数据源是否与默认导出的数据源相同
+func isDefaultSources(names []string) bool {
+	defaults := defaultSources()
+	if len(names) != len(defaults) {
+		return false
+	}
+	for _, src := range defaults {
+		if !slices.Contains(names, src.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectSources 按名称选择数据源，为空时返回默认导出的数据源
+func selectSources(names []string) ([]dataSource, error) {
+	if len(names) == 0 {
+		return defaultSources(), nil
+	}
+	for _, name := range names {
+		if !slices.Contains(SourceNames(), name) {
+			return nil, fmt.Errorf("未知数据源: %s", name)
+		}
+	}
+	selected := make([]dataSource, 0, len(names))
+	for _, src := range dataSources {
+		if slices.Contains(names, src.Name) {
+			selected = append(selected, src)
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+// 慢日志统计默认不导出；与明细同时导出时不计入总条数与SQL指纹排行
+func Test_SlowLogStatsOptIn(t *testing.T) {
+	sources, err := selectSources(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.ContainsFunc(sources, func(s dataSource) bool { return s.Name == "aliyun_stats" }) {
+		t.Fatal("未开启SLOW_LOG_STATS时默认不应导出慢日志统计")
+	}
+	if got := (RunOptions{Report: DefaultReportName, Sources: []string{"grafana", "aliyun"}}).stateReport(); got != DefaultReportName {
+		t.Fatalf("与默认数据源相同时不应单独记录: %s", got)
+	}
+	if got := (RunOptions{Report: DefaultReportName, Sources: []string{"aliyun", "aliyun_stats", "grafana"}}).stateReport(); got == DefaultReportName {
+		t.Fatal("包含可选数据源时应单独记录")
+	}
+
+	selected, err := selectSources([]string{"aliyun", "aliyun_stats"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs := []convertOutput{
+		{Path: "a.csv", Rows: 100, TopFingerprints: []Fingerprint{{TotalTime: 1}}},
+		{Path: "b.csv", Rows: 20, TopFingerprints: []Fingerprint{{TotalTime: 2}}},
+	}
+	data := buildReportData(RunOptions{Report: DefaultReportName}, selected, outputs)
+	if data.TotalRows != 100 || len(data.TopFingerprints) != 1 || len(data.Sources) != 2 {
+		t.Fatalf("统计数据不应重复计数: rows=%d fingerprints=%d", data.TotalRows, len(data.TopFingerprints))
+	}
+
+	// 单独导出统计数据时正常计数
+	stats, _ := selectSources([]string{"aliyun_stats"})
+	if data := buildReportData(RunOptions{}, stats, outputs[1:]); data.TotalRows != 20 {
+		t.Fatalf("单独导出时应计入总条数: %d", data.TotalRows)
+	}
+}
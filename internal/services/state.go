@@ -0,0 +1,312 @@
+package services
+
+import (
+	"cmp"
+	"dailyDataPanel/internal/conf"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StepRecord 单个步骤的完成记录
+type StepRecord struct {
+	Output     string    `json:"output"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// RunRecord 某个（时间窗口, 报表）组合的运行记录
+type RunRecord struct {
+	Window    string                `json:"window"`
+	Report    string                `json:"report"`
+	Steps     map[string]StepRecord `json:"steps"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// StateStore 运行状态存储（本地JSON文件），用于失败重跑时从失败步骤继续
+type StateStore struct {
+	mu   sync.Mutex
+	path string
+	Runs map[string]*RunRecord `json:"runs"`
+	// LastScheduled 各报表最近一次成功执行的调度时间，用于守护进程启动时补跑
+	LastScheduled map[string]time.Time `json:"last_scheduled,omitempty"`
+	// LastSuccess 各报表最近一次成功运行的完成时间
+	LastSuccess map[string]time.Time `json:"last_success,omitempty"`
+	// Issues 各报表最新时间窗口的Issue，用于关闭或关联上一个时间窗口的Issue
+	Issues map[string]IssueRecord `json:"issues,omitempty"`
+	// WindowIssues 各（时间窗口, 报表）新建的Issue IID，同一时间窗口的运行（含仅导出部分数据源的运行）共用
+	WindowIssues map[string]int `json:"window_issues,omitempty"`
+}
+
+// IssueRecord 按时间窗口新建的Issue
+type IssueRecord struct {
+	Window string `json:"window"`
+	IID    int    `json:"iid"`
+}
+
+var (
+	stateStoresMu sync.Mutex
+	stateStores   = make(map[string]*StateStore)
+)
+
+// stateStore 进程内共享的状态存储，调度与控制API触发的运行共用同一份内存状态与锁，避免各自保存时相互覆盖
+func stateStore() (*StateStore, error) {
+	path := stateFilePath()
+	stateStoresMu.Lock()
+	defer stateStoresMu.Unlock()
+	if store, ok := stateStores[path]; ok {
+		return store, nil
+	}
+	store, err := OpenStateStore(path)
+	if err != nil {
+		return nil, err
+	}
+	stateStores[path] = store
+	return store, nil
+}
+
+// OpenStateStore 打开状态文件，不存在则创建空状态
+func OpenStateStore(path string) (*StateStore, error) {
+	store := &StateStore{
+		path: path,
+		Runs: make(map[string]*RunRecord),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("读取状态文件失败: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("解析状态文件失败: %w", err)
+	}
+	if store.Runs == nil {
+		store.Runs = make(map[string]*RunRecord)
+	}
+	return store, nil
+}
+
+// LastScheduledRun 报表最近一次成功执行的调度时间
+func (s *StateStore) LastScheduledRun(report string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.LastScheduled[report]
+	return t, ok
+}
+
+// MarkScheduledRun 记录报表成功执行的调度时间
+func (s *StateStore) MarkScheduledRun(report string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.LastScheduled == nil {
+		s.LastScheduled = make(map[string]time.Time)
+	}
+	s.LastScheduled[report] = t
+	return s.save()
+}
+
+// LastSuccessRun 报表最近一次成功运行的完成时间
+func (s *StateStore) LastSuccessRun(report string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.LastSuccess[report]
+	return t, ok
+}
+
+// MarkSuccess 记录报表成功运行的完成时间
+func (s *StateStore) MarkSuccess(report string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.LastSuccess == nil {
+		s.LastSuccess = make(map[string]time.Time)
+	}
+	s.LastSuccess[report] = t
+	return s.save()
+}
+
+// LastIssue 报表最新时间窗口的Issue
+func (s *StateStore) LastIssue(report string) (IssueRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.Issues[report]
+	return rec, ok
+}
+
+// WindowIssue 报表在时间窗口下新建的Issue
+func (s *StateStore) WindowIssue(window, report string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	iid, ok := s.WindowIssues[runKey(window, report)]
+	return iid, ok
+}
+
+// MarkIssue 记录报表在时间窗口下新建的Issue；补跑更早的时间窗口时不覆盖最新时间窗口的记录
+func (s *StateStore) MarkIssue(report string, rec IssueRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.WindowIssues == nil {
+		s.WindowIssues = make(map[string]int)
+	}
+	s.WindowIssues[runKey(rec.Window, report)] = rec.IID
+	if last, ok := s.Issues[report]; !ok || last.Window <= rec.Window {
+		if s.Issues == nil {
+			s.Issues = make(map[string]IssueRecord)
+		}
+		s.Issues[report] = rec
+	}
+	return s.save()
+}
+
+// defaultStateRetentionDays 运行状态默认保留天数
+const defaultStateRetentionDays = 90
+
+// pruneBefore 删除时间窗口结束日期早于cutoff的运行记录与Issue记录，keep为本次使用的记录（调用方需持有锁）
+func (s *StateStore) pruneBefore(cutoff time.Time, keep string) {
+	expired := func(window string) bool {
+		_, end, ok := strings.Cut(window, "_")
+		if !ok {
+			return false
+		}
+		t, err := time.ParseInLocation(time.DateOnly, end, cutoff.Location())
+		return err == nil && t.Before(cutoff)
+	}
+	for key, record := range s.Runs {
+		if key != keep && expired(record.Window) {
+			delete(s.Runs, key)
+		}
+	}
+	for key := range s.WindowIssues {
+		window, _, _ := strings.Cut(key, "/")
+		if key != keep && expired(window) {
+			delete(s.WindowIssues, key)
+		}
+	}
+}
+
+// stateRetention 运行状态的保留期，为0时不清理
+func stateRetention() time.Duration {
+	days := cmp.Or(conf.GetAppConfig().Global.StateRetentionDays, defaultStateRetentionDays)
+	return time.Duration(max(days, 0)) * 24 * time.Hour
+}
+
+// runKey 生成（时间窗口, 报表）的唯一键
+func runKey(window, report string) string {
+	return window + "/" + report
+}
+
+// save 原子写入状态文件（调用方需持有锁）
+func (s *StateStore) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := pathIsExist(filepath.Dir(s.path)); err != nil {
+		return err
+	}
+	// 临时文件名唯一，避免多个进程同时写入同一个临时文件
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Tracker 获取（时间窗口, 报表）的步骤跟踪器，force为true时清空已有记录
+func (s *StateStore) Tracker(window, report string, force bool) (*StepTracker, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := runKey(window, report)
+	record, ok := s.Runs[key]
+	if !ok || force {
+		record = &RunRecord{
+			Window: window,
+			Report: report,
+			Steps:  make(map[string]StepRecord),
+		}
+		s.Runs[key] = record
+		// 新建记录时顺带清理过期的时间窗口，避免状态文件无限增长
+		if retention := stateRetention(); retention > 0 {
+			s.pruneBefore(time.Now().Add(-retention), key)
+		}
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+	}
+	return &StepTracker{store: s, record: record}, nil
+}
+
+// StepTracker 跟踪单次运行中各步骤的完成情况
+type StepTracker struct {
+	store  *StateStore
+	record *RunRecord
+}
+
+// Done 返回步骤的上次输出以及是否已完成
+func (t *StepTracker) Done(step string) (string, bool) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	rec, ok := t.record.Steps[step]
+	return rec.Output, ok
+}
+
+// DoneSteps 已完成的步骤名称（按名称排序）
+func (t *StepTracker) DoneSteps() []string {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	steps := make([]string, 0, len(t.record.Steps))
+	for step := range t.record.Steps {
+		steps = append(steps, step)
+	}
+	sort.Strings(steps)
+	return steps
+}
+
+// Invalidate 使已完成的步骤失效（例如产物文件已被删除），下次Do时重新执行
+func (t *StepTracker) Invalidate(step string) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	if _, ok := t.record.Steps[step]; !ok {
+		return nil
+	}
+	delete(t.record.Steps, step)
+	return t.store.save()
+}
+
+// Do 执行步骤，已完成的步骤直接返回上次的输出，执行成功后立即持久化
+func (t *StepTracker) Do(step string, fn func() (string, error)) (string, error) {
+	if output, ok := t.Done(step); ok {
+		return output, nil
+	}
+	output, err := fn()
+	if err != nil {
+		return "", err
+	}
+
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	now := time.Now()
+	t.record.Steps[step] = StepRecord{Output: output, FinishedAt: now}
+	t.record.UpdatedAt = now
+	if err := t.store.save(); err != nil {
+		return "", err
+	}
+	return output, nil
+}
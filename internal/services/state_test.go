@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func Test_StepTrackerResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := OpenStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker, err := store.Tracker("2026-10-12_2026-10-18", DefaultReportName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tracker.Do("upload", func() (string, error) { return "![a](/uploads/a.csv)", nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tracker.Do("comment", func() (string, error) { return "", errors.New("boom") }); err == nil {
+		t.Fatal("期望评论步骤失败")
+	}
+
+	// 重新打开状态文件模拟重跑：已完成的上传步骤不再执行
+	store, err = OpenStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker, err = store.Tracker("2026-10-12_2026-10-18", DefaultReportName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	out, err := tracker.Do("upload", func() (string, error) { calls++; return "new", nil })
+	if err != nil || calls != 0 || out != "![a](/uploads/a.csv)" {
+		t.Fatalf("上传步骤应被跳过: out=%q calls=%d err=%v", out, calls, err)
+	}
+	if _, err := tracker.Do("comment", func() (string, error) { calls++; return "", nil }); err != nil || calls != 1 {
+		t.Fatalf("评论步骤应重新执行: calls=%d err=%v", calls, err)
+	}
+
+	// force 清空记录
+	tracker, err = store.Tracker("2026-10-12_2026-10-18", DefaultReportName, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps := tracker.DoneSteps(); len(steps) != 0 {
+		t.Fatalf("force 后不应存在已完成步骤: %v", steps)
+	}
+}
+
+func Test_MarkIssueKeepsLatestWindow(t *testing.T) {
+	store, err := OpenStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MarkIssue(DefaultReportName, IssueRecord{Window: "2026-10-12_2026-10-18", IID: 10}); err != nil {
+		t.Fatal(err)
+	}
+	// 补跑更早的时间窗口
+	if err := store.MarkIssue(DefaultReportName, IssueRecord{Window: "2026-09-28_2026-10-04", IID: 11}); err != nil {
+		t.Fatal(err)
+	}
+	if last, _ := store.LastIssue(DefaultReportName); last.IID != 10 {
+		t.Fatalf("补跑旧时间窗口不应覆盖最新的Issue: %+v", last)
+	}
+	if iid, ok := store.WindowIssue("2026-09-28_2026-10-04", DefaultReportName); !ok || iid != 11 {
+		t.Fatalf("应按时间窗口记录Issue: iid=%d ok=%v", iid, ok)
+	}
+}
+
+// 新建运行记录时清理时间窗口已过保留期的记录
+func Test_TrackerPrunesExpiredWindows(t *testing.T) {
+	store, err := OpenStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := api.WindowBefore(time.Now().AddDate(0, 0, -defaultStateRetentionDays-7), 7).Key()
+	recent := api.WindowBefore(time.Now(), 7).Key()
+	for _, window := range []string{old, recent} {
+		if _, err := store.Tracker(window, DefaultReportName, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.MarkIssue(DefaultReportName, IssueRecord{Window: window, IID: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := store.Runs[runKey(old, DefaultReportName)]; ok {
+		t.Fatal("过期时间窗口的运行记录应被清理")
+	}
+	if _, ok := store.WindowIssue(old, DefaultReportName); ok {
+		t.Fatal("过期时间窗口的Issue记录应被清理")
+	}
+	if _, ok := store.Runs[runKey(recent, DefaultReportName)]; !ok {
+		t.Fatal("保留期内的运行记录不应被清理")
+	}
+}
+
+// 升级前的状态文件中企业微信通知记录为notify步骤，重跑时默认weixin渠道不应重复发送
+func Test_LegacyNotifyStep(t *testing.T) {
+	conf.Logger = zap.NewNop()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("已通知的时间窗口不应重复发送")
+	}))
+	defer srv.Close()
+
+	window := api.WindowBefore(time.Now(), 7).Key()
+	path := filepath.Join(t.TempDir(), "state.json")
+	legacy := `{"runs":{"` + window + `/` + DefaultReportName + `":{"window":"` + window + `","report":"` + DefaultReportName +
+		`","steps":{"upload":{"output":"![a](/uploads/a.csv)"},"comment":{"output":""},"notify":{"output":""}}}}}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker, err := store.Tracker(window, DefaultReportName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := parseTemplate("weixin", `{{.Report}}`, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels := []*channel{{name: "weixin", notifier: &weixinNotifier{robot: api.NewWeixinRobotAPIWithURL(srv.URL)}, tmpl: tmpl, failOnError: true}}
+	if err := notifyChannels(context.Background(), tracker, channels, &ReportData{Report: "weekly"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tracker.Done("notify:weixin"); !ok {
+		t.Fatal("应沿用旧的通知记录")
+	}
+}
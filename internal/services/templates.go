@@ -0,0 +1,244 @@
+package services
+
+import (
+	"bytes"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// ReportData 报表数据模型，GitLab评论与各通知渠道的消息模板（text/template）均基于该结构渲染。
+//
+// 模板中可用的字段：
+//
+//	.RunID            运行ID
+//	.Report           报表名称
+//	.Window           时间窗口，{{.Window}} 输出"2026-10-12至2026-10-18"，另有 .Window.StartDate / .Window.EndDate
+//	.Sources          各数据源导出结果，每项包含 .Name .Label .Rows .FileName .FilePath .Upload .UploadURL，
+//	                  .UploadExpires（UploadURL为签名地址时的过期时间）、.PermanentURL（不会过期的文件地址），
+//	                  以及 .Links（各发布目标的链接，每项包含 .Publisher .URL）
+//	.TotalRows        全部数据源的慢日志条数
+//	.TopFingerprints  按总耗时降序的SQL指纹，每项包含 .Source .SQL .Sample .Count .TotalTime .MaxQueryTime .AvgQueryTime
+//	.IssueURL         GitLab Issue地址（由GITLAB.URL、PROJECT_ID与ISSUE_IID通过API解析）
+//	.NoteURL          本次创建的GitLab评论永久链接（评论创建后可用，GitLab评论模板中为空）
+//	.WikiURL          本时间窗口的GitLab Wiki页面地址（启用GITLAB.WIKI时可用）
+//	.Failures         本次运行中不影响整体结果的失败信息
+//
+// 模板函数：truncate（按字符截断，如 {{truncate 80 .SQL}}）、join（如 {{join .Failures "; "}}）。
+type ReportData struct {
+	RunID           string
+	Report          string
+	Window          api.TimeWindow
+	Sources         []SourceSummary
+	TotalRows       int
+	TopFingerprints []Fingerprint
+	IssueURL        string
+	NoteURL         string
+	WikiURL         string
+	Failures        []string
+}
+
+// SourceSummary 单个数据源的导出结果
+type SourceSummary struct {
+	Name      string // 数据源标识
+	Label     string // 展示名称
+	Rows      int    // 慢日志条数
+	FileName  string // 导出文件名
+	FilePath  string // 导出文件本地路径
+	Upload    string // 上传结果（Markdown引用文本）
+	UploadURL string // 上传文件的绝对地址（第一个发布成功的目标）
+	Links     []PublishedLink
+
+	UploadExpires time.Time // UploadURL为签名地址时的过期时间，否则为零值
+	PermanentURL  string    // 第一个非签名地址的发布链接，用于Wiki等长期保存的页面；全部为签名地址时为空
+}
+
+// 默认GitLab评论模板
+const defaultCommentTemplate = `## {{.Window}} MySQL慢日志数据导出
+{{range .Sources}}> {{.Label}}：{{.Upload}}{{if not .UploadExpires.IsZero}}（链接有效期至 {{.UploadExpires.Format "2006-01-02 15:04"}}）{{end}}
+
+{{end}}`
+
+var templateFuncs = template.FuncMap{
+	"truncate": truncate,
+	"join":     strings.Join,
+}
+
+// truncate 按字符截断
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
+
+// truncateBytes 按字节截断（不截断多字节字符），截断后含省略号不超过limit字节
+func truncateBytes(limit int, s string) string {
+	if len(s) <= limit {
+		return s
+	}
+	cut := max(limit-len("..."), 0)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+var (
+	htmlTagPattern  = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdLinkPattern   = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	mdMarkerPattern = regexp.MustCompile("(?m)^[ \t]*(?:#{1,6}[ \t]+|>[ \t]?|[-*][ \t]+)|\\*\\*|__|`")
+)
+
+// plainText 去除渲染后消息中的HTML标签与Markdown标记及空行，用于仅支持纯文本的字段
+func plainText(message string) string {
+	s := htmlTagPattern.ReplaceAllString(message, "")
+	s = mdLinkPattern.ReplaceAllString(s, "$1")
+	s = mdMarkerPattern.ReplaceAllString(s, "")
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseTemplate 解析模板，file优先于text，两者均为空时使用fallback
+func parseTemplate(name, text, file, fallback string) (*template.Template, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取模板文件失败: %w", err)
+		}
+		text = string(data)
+	}
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("解析模板 %s 失败: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// parseHTMLTemplate 解析HTML模板（html/template），渲染时按上下文自动转义数据，用于HTML正文的渠道
+func parseHTMLTemplate(name, text, file, fallback string) (*htmltemplate.Template, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取模板文件失败: %w", err)
+		}
+		text = string(data)
+	}
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(templateFuncs)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("解析模板 %s 失败: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// messageTemplate 已解析的模板（text/template或html/template）
+type messageTemplate interface {
+	Name() string
+	Execute(w io.Writer, data any) error
+}
+
+// executeTemplate 渲染模板
+func executeTemplate(tmpl messageTemplate, data *ReportData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染模板 %s 失败: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// commentTemplate GitLab评论模板
+func commentTemplate() (*template.Template, error) {
+	appConf := conf.GetAppConfig()
+	return parseTemplate("comment", appConf.Templates.Comment, appConf.Templates.CommentFile, defaultCommentTemplate)
+}
+
+// topN 消息中展示的SQL指纹数量
+func topN() int {
+	if n := conf.GetAppConfig().Templates.TopN; n > 0 {
+		return n
+	}
+	return 10
+}
+
+var markdownLinkTarget = regexp.MustCompile(`\]\(([^)\s]+)\)`)
+
+// markdownURL 提取Markdown链接中的地址
+func markdownURL(md string) string {
+	if m := markdownLinkTarget.FindStringSubmatch(md); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// SampleReportData 模板预览使用的示例数据
+func SampleReportData() *ReportData {
+	window := api.WindowBefore(time.Now(), 7)
+	data := &ReportData{
+		RunID:    "0123456789abcdef",
+		Report:   DefaultReportName,
+		Window:   window,
+		IssueURL: "https://gitlab.example.com/ops/public/-/issues/9",
+		NoteURL:  "https://gitlab.example.com/ops/public/-/issues/9#note_12345",
+		WikiURL:  "https://gitlab.example.com/ops/public/-/wikis/mysql-slow-log/" + window.Key(),
+		Failures: []string{"通知渠道 slack 发送失败: context deadline exceeded"},
+	}
+	for _, src := range defaultSources() {
+		file := src.FileName + "_20260101000000.csv"
+		data.Sources = append(data.Sources, SourceSummary{
+			Name:      src.Name,
+			Label:     src.Label,
+			Rows:      1280,
+			FileName:  file,
+			Upload:    fmt.Sprintf("[%s](/uploads/0123456789abcdef/%s)", file, file),
+			UploadURL: "https://gitlab.example.com/ops/public/uploads/0123456789abcdef/" + file,
+		})
+		data.TotalRows += 1280
+	}
+	data.TopFingerprints = topFingerprints(dataSources[0].Label, []SlowQuery{
+		{SQL: "SELECT * FROM orders WHERE user_id = 1001 AND status IN (1, 2, 3)", QueryTime: 12.5},
+		{SQL: "SELECT * FROM orders WHERE user_id = 2002 AND status IN (4)", QueryTime: 8.1},
+		{SQL: "UPDATE inventory SET stock = stock - 1 WHERE sku = 'A-100'", QueryTime: 3.2},
+	}, topN())
+	return data
+}
+
+// PreviewTemplate 使用示例数据渲染模板：指定模板文件时渲染该文件，
+// 指定通知渠道名称时渲染该渠道的模板，否则渲染GitLab评论模板
+func PreviewTemplate(templateFile, channelName string) (string, error) {
+	var (
+		tmpl messageTemplate
+		err  error
+	)
+	switch {
+	case templateFile != "":
+		tmpl, err = parseTemplate(templateFile, "", templateFile, "")
+	case channelName != "":
+		tmpl, err = channelTemplate(channelName)
+	default:
+		tmpl, err = commentTemplate()
+	}
+	if err != nil {
+		return "", err
+	}
+	return executeTemplate(tmpl, SampleReportData())
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"dailyDataPanel/internal/api"
+	"dailyDataPanel/internal/conf"
+	"time"
+)
+
+// WebhookPayloadVersion 通用Webhook文档版本，字段有不兼容变更时递增
+const WebhookPayloadVersion = 1
+
+// WebhookPayload 通用Webhook发送的JSON文档
+type WebhookPayload struct {
+	Version         int                  `json:"version"`
+	Event           string               `json:"event"`
+	RunID           string               `json:"run_id"`
+	Report          string               `json:"report"`
+	Window          WebhookWindow        `json:"window"`
+	Sources         []WebhookSource      `json:"sources"`
+	TotalRows       int                  `json:"total_rows"`
+	TopFingerprints []WebhookFingerprint `json:"top_fingerprints"`
+	IssueURL        string               `json:"issue_url,omitempty"`
+	NoteURL         string               `json:"note_url,omitempty"`
+	Message         string               `json:"message,omitempty"` // 按渠道模板渲染的文本
+	Errors          []string             `json:"errors"`
+	SentAt          time.Time            `json:"sent_at"`
+}
+
+// WebhookWindow 时间窗口（日期均为yyyy-mm-dd，含首尾）
+type WebhookWindow struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// WebhookSource 单个数据源的导出结果
+type WebhookSource struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Rows     int    `json:"rows"`
+	FileName string `json:"file_name"`
+	FileURL  string `json:"file_url,omitempty"`
+}
+
+// WebhookFingerprint SQL指纹统计
+type WebhookFingerprint struct {
+	Source       string  `json:"source"`
+	SQL          string  `json:"sql"`
+	Sample       string  `json:"sample"`
+	Count        int     `json:"count"`
+	TotalTime    float64 `json:"total_time"`
+	MaxQueryTime float64 `json:"max_query_time"`
+	AvgQueryTime float64 `json:"avg_query_time"`
+}
+
+// newWebhookPayload 由报表数据生成Webhook文档
+func newWebhookPayload(message string, data *ReportData) *WebhookPayload {
+	payload := &WebhookPayload{
+		Version:         WebhookPayloadVersion,
+		Event:           "report.completed",
+		RunID:           data.RunID,
+		Report:          data.Report,
+		Window:          WebhookWindow{StartDate: data.Window.StartDate(), EndDate: data.Window.EndDate()},
+		Sources:         []WebhookSource{},
+		TotalRows:       data.TotalRows,
+		TopFingerprints: []WebhookFingerprint{},
+		IssueURL:        data.IssueURL,
+		NoteURL:         data.NoteURL,
+		Message:         message,
+		Errors:          append([]string{}, data.Failures...),
+		SentAt:          time.Now(),
+	}
+	for _, src := range data.Sources {
+		payload.Sources = append(payload.Sources, WebhookSource{
+			Name:     src.Name,
+			Label:    src.Label,
+			Rows:     src.Rows,
+			FileName: src.FileName,
+			FileURL:  src.UploadURL,
+		})
+	}
+	for _, fp := range data.TopFingerprints {
+		payload.TopFingerprints = append(payload.TopFingerprints, WebhookFingerprint{
+			Source:       fp.Source,
+			SQL:          fp.SQL,
+			Sample:       fp.Sample,
+			Count:        fp.Count,
+			TotalTime:    fp.TotalTime,
+			MaxQueryTime: fp.MaxQueryTime,
+			AvgQueryTime: fp.AvgQueryTime(),
+		})
+	}
+	return payload
+}
+
+// webhookNotifier 通用出站Webhook
+type webhookNotifier struct {
+	webhook *api.WebhookAPI
+}
+
+func (w *webhookNotifier) Notify(ctx context.Context, message string, data *ReportData) error {
+	return w.webhook.Send(ctx, newWebhookPayload(message, data))
+}
+
+func init() {
+	RegisterNotifier("webhook",
+		"MySQL慢查询数据报表 {{.Window}} 已更新，共{{.TotalRows}}条慢日志",
+		func(cfg conf.NotifierConfig) (Notifier, error) {
+			webhook := conf.GetAppConfig().Webhook
+			url, err := webhookURL(cfg, webhook.URL)
+			if err != nil {
+				return nil, err
+			}
+			retry := api.DefaultRetryPolicy()
+			if webhook.MaxAttempts > 0 {
+				retry.MaxAttempts = webhook.MaxAttempts
+			}
+			return &webhookNotifier{
+				webhook: api.NewWebhookAPI(url, cmp.Or(cfg.Secret, webhook.Secret), webhook.SignatureHeader, retry),
+			}, nil
+		})
+}